@@ -2,10 +2,15 @@ package yarnstart
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/BurntSushi/toml"
 	"github.com/paketo-buildpacks/libnodejs"
 	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/fs"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
 )
 
@@ -18,92 +23,689 @@ func Build(logger scribe.Emitter) packit.BuildFunc {
 			return packit.BuildResult{}, err
 		}
 
-		pkg, err := libnodejs.ParsePackageJSON(projectPath)
+		pkg, err := parsePackageJSON(projectPath)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		command := "node"
-		arg := fmt.Sprintf("node %s", filepath.Join(context.WorkingDir, "server.js"))
+		// The start command comes from the selected workspace, if any, and runs
+		// in its directory. Everything that belongs to the project as a whole,
+		// such as the Plug'n'Play runtime, is still found at the project path.
+		startPath, startPkg := projectPath, pkg
+		if name := startWorkspaceName(); name != "" {
+			member, found, err := findWorkspace(projectPath, pkg, name)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if !found {
+				return packit.BuildResult{}, fmt.Errorf("no workspace named %q found in %s", name, projectPath)
+			}
 
-		if pkg.Scripts.Start != "" {
-			command = "bash"
-			arg = pkg.Scripts.Start
+			rel, err := filepath.Rel(projectPath, member.Path)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.Process("Starting workspace %s in %s", name, rel)
+			logger.Break()
+
+			startPath, startPkg = member.Path, member.Package
 		}
 
-		if pkg.Scripts.PreStart != "" {
-			command = "bash"
-			arg = fmt.Sprintf("%s && %s", pkg.Scripts.PreStart, arg)
+		scriptName := startScriptName()
+
+		shouldStartWorkspaces, err := checkWorkspaceProcessesEnabled()
+		if err != nil {
+			return packit.BuildResult{}, err
 		}
 
-		if pkg.Scripts.PostStart != "" {
-			command = "bash"
-			arg = fmt.Sprintf("%s && %s", arg, pkg.Scripts.PostStart)
+		var members []workspace
+		if shouldStartWorkspaces {
+			members, err = startableWorkspaces(projectPath, pkg, scriptName)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
 		}
 
-		// Ideally we would like the lifecycle to support setting a custom working
-		// directory to run the launch process.  Until that happens we will cd in.
-		if projectPath != context.WorkingDir {
-			command = "bash"
-			arg = fmt.Sprintf("cd %s && %s", projectPath, arg)
+		rootPath, err := workspaceRootPath(context.WorkingDir, projectPath)
+		if err != nil {
+			return packit.BuildResult{}, err
 		}
 
-		args := []string{arg}
-		switch command {
-		case "bash":
-			args = []string{"-c", arg}
-		case "node":
-			args = []string{filepath.Join(context.WorkingDir, "server.js")}
+		rootPkg := pkg
+		if rootPath != projectPath {
+			rootPkg, err = parsePackageJSON(rootPath)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
 		}
 
-		processes := []packit.Process{
-			{
-				Type:    "web",
-				Command: command,
-				Args:    args,
-				Default: true,
-				Direct:  true,
-			},
+		inliner, err := newScriptInliner(rootPath, rootPkg)
+		if err != nil {
+			return packit.BuildResult{}, err
 		}
 
-		shouldReload, err := checkLiveReloadEnabled()
+		packageManager := pkg.PackageManager
+		if packageManager == "" {
+			packageManager = rootPkg.PackageManager
+		}
+
+		mode, reason, err := startMode(rootPath, packageManager)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		if shouldReload {
+		if mode == startModeYarn {
+			logger.Process("Launching through Yarn, since %s", reason)
+			logger.Break()
+		}
+
+		scriptSegments := func(path string, pkg packageJSON, name, fallback string) ([]string, error) {
+			return launchSegments(inliner, mode, path, pkg, name, fallback)
+		}
+
+		// A start script that does nothing but run the script of another
+		// workspace is replaced by that script, which the web process then runs
+		// from the directory of that workspace.
+		web, err := resolveWebCommand(inliner, mode, startPath, startPkg, scriptName)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		resolved := scriptName
+		for _, target := range web.Targets {
+			rel, err := filepath.Rel(projectPath, target.Path)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.Process("Resolving the %q script to the %q script of workspace %s in %s", resolved, target.Script, target.Package.Name, rel)
+			logger.Break()
+
+			resolved = target.Script
+		}
+
+		webPath, webPkg, webScript := web.Path, web.Package, web.Script
+
+		// A project whose services all live in workspaces does not need a start
+		// command of its own when those workspaces get their own processes.
+		startScript := webPkg.Scripts[webScript]
+		switch {
+		case web.Fallback != "":
+			moduleType := "CommonJS"
+			if web.Entrypoint.Module {
+				moduleType = "ES module"
+			}
+
+			logger.Process("No %q script found in package.json", scriptName)
+			logger.Subprocess("Using %s from %s as the entrypoint (%s)", web.Entrypoint.Path, web.Entrypoint.Source, moduleType)
+			logger.Break()
+
+			startScript = web.Fallback
+
+		case web.Segments == nil && len(members) == 0 && scriptName != "start":
+			return packit.BuildResult{}, fmt.Errorf("no %q script in package.json", scriptName)
+
+		case web.Segments == nil && len(members) == 0:
+			return packit.BuildResult{}, fmt.Errorf("no %q script in package.json and no entrypoint found: expected the \"main\" field, a single \"bin\" entry or one of %s to exist", scriptName, strings.Join(entrypointFiles, ", "))
+		}
+
+		major, minor, err := buildpackAPI(context.CNBPath)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		directProcesses := major > 0 || minor >= 9
+
+		// Buildpack API 0.8 and above let a launch process declare its own
+		// working directory. Older APIs always launch from the application
+		// directory, so the command has to cd into the directory instead.
+		workingDirectories := major > 0 || minor >= 8
+		processDirs := func(path string) (cdDir, processDir string) {
+			switch {
+			case path == context.WorkingDir:
+				return "", ""
+			case workingDirectories:
+				return "", path
+			default:
+				return path, ""
+			}
+		}
+
+		shouldLiftEnv, err := checkLiftEnvEnabled()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		// Variables assigned in front of a command, directly or through
+		// cross-env, are moved into the environment of the process instead, so
+		// that the command needs neither bash nor cross-env to run.
+		liftEnv := func(segments []string) (map[string]string, []string) {
+			if !shouldLiftEnv {
+				return nil, segments
+			}
+
+			return liftEnvironment(segments)
+		}
+
+		cdDir, processDir := processDirs(startPath)
+		webCdDir, webProcessDir := processDirs(webPath)
+
+		pnp, err := usesPnP(rootPath)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		// Each process gets the environment that Yarn would have set up for the
+		// script it runs, including the executables of the package and of its
+		// workspace root on the PATH. Plug'n'Play projects have no node_modules
+		// to take executables from. In the yarn mode, Yarn sets it up itself.
+		processEnvs := map[string]packit.Environment{}
+		var binPaths []string
+		addProcessEnv := func(processType string, pkg packageJSON, path, event, script string, assignments map[string]string) error {
+			if mode == startModeYarn {
+				return nil
+			}
+
+			env, err := pkg.lifecycleEnv(event, script)
+			if err != nil {
+				return err
+			}
+
+			for name, value := range assignments {
+				env.Override(name, value)
+			}
+
+			if !pnp {
+				bins := []string{filepath.Join(path, NodeModules, ".bin")}
+				if rootPath != path {
+					bins = append(bins, filepath.Join(rootPath, NodeModules, ".bin"))
+				}
+
+				for _, bin := range bins {
+					if !slices.Contains(binPaths, bin) {
+						binPaths = append(binPaths, bin)
+					}
+				}
+
+				env.Prepend("PATH", strings.Join(bins, string(os.PathListSeparator)), string(os.PathListSeparator))
+			}
+
+			processEnvs[processType] = env
+			return nil
+		}
+
+		var (
+			processes []packit.Process
+
+			// scriptLayers hold the scripts that the processes run, and come after
+			// the layers of the launch environment and the launch init.
+			scriptLayers []packit.Layer
+		)
+		if web.Segments != nil {
+			assignments, segments := web.Assignments, web.Segments
+			command, args := composeCommand(webCdDir, segments)
+
 			processes = []packit.Process{
 				{
-					Type:    "web",
-					Command: "watchexec",
-					Args: append([]string{
-						"--restart",
-						"--shell", "none",
-						"--watch", projectPath,
-						"--ignore", filepath.Join(projectPath, "package.json"),
-						"--ignore", filepath.Join(projectPath, "yarn.lock"),
-						"--ignore", filepath.Join(projectPath, "node_modules"),
-						"--",
-						command,
-					}, args...),
-					Default: true,
-					Direct:  true,
-				},
-				{
-					Type:    "no-reload",
-					Command: command,
-					Args:    args,
-					Direct:  true,
+					Type:             "web",
+					Command:          command,
+					Args:             args,
+					Default:          true,
+					Direct:           true,
+					WorkingDirectory: webProcessDir,
 				},
 			}
+
+			shouldReload, err := checkLiveReloadEnabled()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if shouldReload {
+				engine, fields, reason, err := liveReloadEngine(segments)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				if reason != "" {
+					logger.Process("Using watchexec for live reload, since %s", reason)
+					logger.Break()
+				}
+
+				// node --watch reloads a plain node command without the watchexec
+				// that has to be installed for anything else.
+				var (
+					reloadCommand string
+					reloadArgs    []string
+				)
+				if engine == liveReloadEngineNode {
+					watches, err := liveReloadWatchPaths(projectPath)
+					if err != nil {
+						return packit.BuildResult{}, err
+					}
+
+					words := []string{Node}
+					for _, watch := range watches {
+						words = append(words, "--watch-path="+watch)
+					}
+
+					reloadCommand, reloadArgs = composeCommand(webCdDir, []string{shellJoin(append(words, fields[1:]...)...)})
+				} else {
+					shouldInstall, err := checkLiveReloadInstallEnabled()
+					if err != nil {
+						return packit.BuildResult{}, err
+					}
+
+					// The dependencies are reinstalled at the workspace root, which
+					// is where Yarn keeps the lockfile of a workspace member.
+					var manifests []string
+					if shouldInstall {
+						for _, manifest := range []string{
+							filepath.Join(projectPath, "package.json"),
+							filepath.Join(rootPath, "package.json"),
+							filepath.Join(rootPath, "yarn.lock"),
+						} {
+							if !slices.Contains(manifests, manifest) {
+								manifests = append(manifests, manifest)
+							}
+						}
+					}
+
+					watchArgs, err := watchexecArgs(projectPath, manifests)
+					if err != nil {
+						return packit.BuildResult{}, err
+					}
+
+					reloadCommand, reloadArgs = "watchexec", append(watchArgs, "--")
+
+					if shouldInstall {
+						berry, err := yarnBerryReason(rootPath, packageManager)
+						if err != nil {
+							return packit.BuildResult{}, err
+						}
+
+						layer, err := context.Layers.Get(LiveReloadInstall)
+						if err != nil {
+							return packit.BuildResult{}, err
+						}
+
+						layer, err = layer.Reset()
+						if err != nil {
+							return packit.BuildResult{}, err
+						}
+
+						layer.Launch = true
+
+						err = os.MkdirAll(filepath.Join(layer.Path, "bin"), os.ModePerm)
+						if err != nil {
+							return packit.BuildResult{}, fmt.Errorf("failed to create live reload install directory: %w", err)
+						}
+
+						// The snapshot records the manifests that the dependencies
+						// were installed from during the build, so that only a
+						// change made afterwards triggers a reinstall.
+						snapshot, err := manifestSnapshot(manifests)
+						if err != nil {
+							return packit.BuildResult{}, err
+						}
+
+						snapshotPath := filepath.Join(layer.Path, "manifests")
+						err = os.WriteFile(snapshotPath, snapshot, 0644)
+						if err != nil {
+							return packit.BuildResult{}, fmt.Errorf("failed to write the manifest snapshot: %w", err)
+						}
+
+						scriptPath := filepath.Join(layer.Path, "bin", "reinstall")
+						script := reinstallScript(rootPath, manifests, snapshotPath, yarnInstallCommand(berry != ""))
+						err = os.WriteFile(scriptPath, []byte(script), 0755)
+						if err != nil {
+							return packit.BuildResult{}, fmt.Errorf("failed to write the reinstall script: %w", err)
+						}
+
+						logger.Process("Reinstalling the dependencies on live reload whenever package.json or yarn.lock change")
+						logger.Break()
+
+						reloadArgs = append(reloadArgs, scriptPath)
+						scriptLayers = append(scriptLayers, layer)
+					}
+
+					reloadArgs = append(append(reloadArgs, command), args...)
+				}
+
+				processes = []packit.Process{
+					{
+						Type:             "web",
+						Command:          reloadCommand,
+						Args:             reloadArgs,
+						Default:          true,
+						Direct:           true,
+						WorkingDirectory: webProcessDir,
+					},
+					{
+						Type:             "no-reload",
+						Command:          command,
+						Args:             args,
+						Direct:           true,
+						WorkingDirectory: webProcessDir,
+					},
+				}
+			}
+
+			shouldDebug, err := checkDebugEnabled()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			// The debug processes run the same commands as the processes above,
+			// with a script preloaded through NODE_OPTIONS that opens the
+			// inspector, so that it reaches node even when the command starts it
+			// through bash or yarn.
+			var (
+				debugTypes  []string
+				preloadPath string
+			)
+			if shouldDebug {
+				port, err := debugPort()
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				layer, err := context.Layers.Get(DebugPreload)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				layer, err = layer.Reset()
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				layer.Launch = true
+
+				preloadPath = filepath.Join(layer.Path, "inspect.js")
+				err = os.WriteFile(preloadPath, []byte(debugPreload(port)), 0644)
+				if err != nil {
+					return packit.BuildResult{}, fmt.Errorf("failed to write the debug preload script: %w", err)
+				}
+
+				scriptLayers = append(scriptLayers, layer)
+
+				for _, process := range slices.Clone(processes) {
+					if process.Type == "web" && shouldReload {
+						process.Type = "debug-reload"
+					} else {
+						process.Type = "debug"
+					}
+
+					process.Default = false
+					processes = append(processes, process)
+					debugTypes = append(debugTypes, process.Type)
+				}
+
+				logger.Process("Adding the %s processes with the Node.js inspector", strings.Join(debugTypes, " and "))
+				logger.Subprocess("Expose port %s of the container to attach a debugger, or the port that BP_NODE_DEBUG_PORT sets at launch", port)
+				logger.Break()
+			}
+
+			for _, process := range processes {
+				err = addProcessEnv(process.Type, webPkg, webPath, webScript, startScript, assignments)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
+			for _, processType := range debugTypes {
+				env, ok := processEnvs[processType]
+				if !ok {
+					env = packit.Environment{}
+				}
+
+				env.Prepend("NODE_OPTIONS", nodeOption("--require", preloadPath), " ")
+				processEnvs[processType] = env
+			}
+		} else {
+			// Live reload and the debug processes only apply to the web process,
+			// which a project whose services all live in workspaces does not have.
+			for _, option := range []struct {
+				Name  string
+				Check func() (bool, error)
+			}{
+				{Name: "BP_LIVE_RELOAD_ENABLED", Check: checkLiveReloadEnabled},
+				{Name: "BP_DEBUG_ENABLED", Check: checkDebugEnabled},
+			} {
+				enabled, err := option.Check()
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				if enabled {
+					logger.Process("Ignoring %s, since there is no %q script for the web process", option.Name, scriptName)
+					logger.Break()
+				}
+			}
+		}
+
+		processScripts, err := parseProcessScripts()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		for _, processScript := range processScripts {
+			if !startPkg.hasScript(processScript.Script) {
+				return packit.BuildResult{}, fmt.Errorf("failed to add the %s process: no %q script in package.json", processScript.Type, processScript.Script)
+			}
+
+			if hasProcess(processes, processScript.Type) {
+				return packit.BuildResult{}, fmt.Errorf("failed to add the %s process: process type %q is already in use", processScript.Type, processScript.Type)
+			}
+
+			segments, err := scriptSegments(startPath, startPkg, processScript.Script, "")
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			assignments, segments := liftEnv(segments)
+			command, args := composeCommand(cdDir, segments)
+			processes = append(processes, packit.Process{
+				Type:             processScript.Type,
+				Command:          command,
+				Args:             args,
+				Direct:           true,
+				WorkingDirectory: processDir,
+			})
+
+			err = addProcessEnv(processScript.Type, startPkg, startPath, processScript.Script, startPkg.Scripts[processScript.Script], assignments)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		if len(members) > 0 {
+			logger.Process("Adding processes for workspaces")
+			for _, member := range members {
+				processType := workspaceProcessType(member)
+				if hasProcess(processes, processType) {
+					return packit.BuildResult{}, fmt.Errorf("failed to add a process for workspace %s: process type %q is already in use", member.Package.Name, processType)
+				}
+
+				rel, err := filepath.Rel(projectPath, member.Path)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+				logger.Subprocess("%s: %s", processType, rel)
+
+				segments, err := scriptSegments(member.Path, member.Package, scriptName, "")
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				cdDir, processDir := processDirs(member.Path)
+				assignments, segments := liftEnv(segments)
+				command, args := composeCommand(cdDir, segments)
+				processes = append(processes, packit.Process{
+					Type:             processType,
+					Command:          command,
+					Args:             args,
+					Direct:           true,
+					WorkingDirectory: processDir,
+				})
+
+				err = addProcessEnv(processType, member.Package, member.Path, scriptName, member.Package.Scripts[scriptName], assignments)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+			logger.Break()
+		}
+
+		err = setDefaultProcess(processes)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if len(binPaths) > 0 {
+			logger.Process("Adding package executables to PATH")
+			for _, bin := range binPaths {
+				logger.Subprocess("%s", bin)
+			}
+			logger.Break()
+		}
+
+		launchEnv := packit.Environment{}
+
+		if pnp && mode == startModeDirect {
+			nodeOptions, err := pnpNodeOptions(rootPath)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.Process("Configuring Yarn Plug'n'Play module resolution")
+			launchEnv.Prepend("NODE_OPTIONS", nodeOptions, " ")
+		}
+
+		var layers []packit.Layer
+		if len(launchEnv) > 0 || len(processEnvs) > 0 {
+			layer, err := context.Layers.Get(LaunchEnv)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			layer, err = layer.Reset()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			layer.Launch = true
+			layer.LaunchEnv = launchEnv
+			layer.ProcessLaunchEnv = processEnvs
+
+			logger.EnvironmentVariables(layer)
+
+			layers = append(layers, layer)
+		}
+
+		shouldInit, err := checkInitEnabled()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if shouldInit {
+			layer, err := context.Layers.Get(LaunchInit)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			layer, err = layer.Reset()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			layer.Launch = true
+
+			err = os.MkdirAll(filepath.Join(layer.Path, "bin"), os.ModePerm)
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to create launch init directory: %w", err)
+			}
+
+			initPath := filepath.Join(layer.Path, "bin", LaunchInit)
+			err = fs.Copy(filepath.Join(context.CNBPath, "bin", LaunchInit), initPath)
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to install launch init: %w", err)
+			}
+
+			// The init forwards signals to the process group of the wrapped command
+			// and reaps any orphaned children, so that the launch process shuts down
+			// gracefully even when it runs through bash.
+			for i, process := range processes {
+				processes[i].Command = initPath
+				processes[i].Args = append([]string{process.Command}, process.Args...)
+			}
+
+			layers = append(layers, layer)
+		}
+
+		layers = append(layers, scriptLayers...)
+
+		if directProcesses {
+			launchProcesses := toDirectProcesses(processes)
+			logger.LaunchDirectProcesses(launchProcesses, processEnvs)
+
+			return packit.BuildResult{
+				Layers: layers,
+				Launch: packit.LaunchMetadata{
+					DirectProcesses: launchProcesses,
+				},
+			}, nil
 		}
 
-		logger.LaunchProcesses(processes)
+		logger.LaunchProcesses(processes, processEnvs)
 
 		return packit.BuildResult{
+			Layers: layers,
 			Launch: packit.LaunchMetadata{
 				Processes: processes,
 			},
 		}, nil
 	}
 }
+
+// buildpackAPI returns the major and minor version of the Buildpack API
+// declared in the buildpack.toml at cnbPath. From 0.9 on, launch processes
+// must be returned as direct processes.
+func buildpackAPI(cnbPath string) (int, int, error) {
+	var buildpack struct {
+		API string `toml:"api"`
+	}
+
+	_, err := toml.DecodeFile(filepath.Join(cnbPath, "buildpack.toml"), &buildpack)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse buildpack.toml: %w", err)
+	}
+
+	var major, minor int
+	_, err = fmt.Sscanf(buildpack.API, "%d.%d", &major, &minor)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse buildpack API version %q: %w", buildpack.API, err)
+	}
+
+	return major, minor, nil
+}
+
+// toDirectProcesses converts processes to the form required by Buildpack API
+// 0.9 and above. The arguments become part of the command so that arguments
+// given to the container are appended to them rather than replacing them.
+func toDirectProcesses(processes []packit.Process) []packit.DirectProcess {
+	var directProcesses []packit.DirectProcess
+	for _, process := range processes {
+		directProcesses = append(directProcesses, packit.DirectProcess{
+			Type:             process.Type,
+			Command:          append([]string{process.Command}, process.Args...),
+			Default:          process.Default,
+			WorkingDirectory: process.WorkingDirectory,
+		})
+	}
+
+	return directProcesses
+}
@@ -2,9 +2,12 @@ package yarnstart_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/paketo-buildpacks/packit/v2"
@@ -35,6 +38,8 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		cnbDir, err = os.MkdirTemp("", "cnb")
 		Expect(err).NotTo(HaveOccurred())
 
+		Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.7"`), 0600)).To(Succeed())
+
 		workingDir, err = os.MkdirTemp("", "working-dir")
 		Expect(err).NotTo(HaveOccurred())
 
@@ -48,6 +53,8 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		}`), 0600)
 		Expect(err).NotTo(HaveOccurred())
 
+		t.Setenv("BP_YARN_START_INIT", "false")
+
 		buffer = bytes.NewBuffer(nil)
 		logger := scribe.NewEmitter(buffer)
 
@@ -89,13 +96,31 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 							Command: "bash",
 							Args: []string{
 								"-c",
-								fmt.Sprintf("cd %s/some-project-dir && some-prestart-command && some-start-command && some-poststart-command", workingDir),
+								fmt.Sprintf("cd %s/some-project-dir && some-prestart-command && some-start-command && exec some-poststart-command", workingDir),
 							},
 							Default: true,
 							Direct:  true,
 						},
 					},
 				},
+				Layers: []packit.Layer{
+					{
+						Path:      filepath.Join(layersDir, "launch-env"),
+						Name:      "launch-env",
+						Launch:    true,
+						SharedEnv: packit.Environment{},
+						BuildEnv:  packit.Environment{},
+						LaunchEnv: packit.Environment{},
+						ProcessLaunchEnv: map[string]packit.Environment{
+							"web": {
+								"npm_lifecycle_event.override":  "start",
+								"npm_lifecycle_script.override": "some-start-command",
+								"PATH.prepend":                  filepath.Join(workingDir, "some-project-dir", "node_modules", ".bin"),
+								"PATH.delim":                    ":",
+							},
+						},
+					},
+				},
 			}))
 		})
 	})
@@ -133,9 +158,11 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 						"--ignore", filepath.Join(workingDir, "some-project-dir", "package.json"),
 						"--ignore", filepath.Join(workingDir, "some-project-dir", "yarn.lock"),
 						"--ignore", filepath.Join(workingDir, "some-project-dir", "node_modules"),
+						"--ignore", filepath.Join(workingDir, "some-project-dir", "**", "node_modules"),
+						"--ignore", filepath.Join(workingDir, "some-project-dir", ".git"),
 						"--",
 						"bash", "-c",
-						fmt.Sprintf("cd %s/some-project-dir && some-prestart-command && some-start-command && some-poststart-command", workingDir),
+						fmt.Sprintf("cd %s/some-project-dir && some-prestart-command && some-start-command && exec some-poststart-command", workingDir),
 					},
 					Default: true,
 					Direct:  true,
@@ -145,171 +172,419 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					Command: "bash",
 					Args: []string{
 						"-c",
-						fmt.Sprintf("cd %s/some-project-dir && some-prestart-command && some-start-command && some-poststart-command", workingDir),
+						fmt.Sprintf("cd %s/some-project-dir && some-prestart-command && some-start-command && exec some-poststart-command", workingDir),
 					},
 					Direct: true,
 				},
 			}))
 		})
-	})
 
-	context("when the package.json does not include a prestart command", func() {
-		it.Before(func() {
-			err := os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
-				"scripts": {
-					"start": "some-start-command",
-					"poststart": "some-poststart-command"
+		context("when BP_LIVE_RELOAD_ENGINE is node", func() {
+			it.Before(func() {
+				t.Setenv("BP_LIVE_RELOAD_ENGINE", "node")
+				Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.10"`), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"scripts": {
+						"start": "NODE_ENV=development node --enable-source-maps server.js"
+					}
+				}`), 0600)).To(Succeed())
+			})
+
+			var buildProcesses = func() []packit.DirectProcess {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				return result.Launch.DirectProcesses
+			}
+
+			it("reloads a plain node command with node --watch-path", func() {
+				projectPath := filepath.Join(workingDir, "some-project-dir")
+				Expect(buildProcesses()).To(Equal([]packit.DirectProcess{
+					{
+						Type:             "web",
+						Command:          []string{"node", "--watch-path=" + projectPath, "--enable-source-maps", "server.js"},
+						Default:          true,
+						WorkingDirectory: projectPath,
+					},
+					{
+						Type:             "no-reload",
+						Command:          []string{"node", "--enable-source-maps", "server.js"},
+						WorkingDirectory: projectPath,
+					},
+				}))
+			})
+
+			it("falls back to watchexec for other commands and logs why", func() {
+				for _, example := range []struct {
+					start  string
+					reason string
+				}{
+					{start: "node migrate.js && node server.js", reason: "the start command is not a plain node command"},
+					{start: "next start", reason: "the start command is not a plain node command"},
+					{start: "node --eval 'require(\"./server\")'", reason: "node --watch does not support --eval"},
+					{start: "node --inspect", reason: "the start command does not name a script for node to run"},
+				} {
+					Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(fmt.Sprintf(`{"scripts": {"start": %q}}`, example.start)), 0600)).To(Succeed())
+
+					Expect(buildProcesses()[0].Command[0]).To(Equal("watchexec"), example.start)
+					Expect(buffer.String()).To(ContainSubstring("Using watchexec for live reload, since "+example.reason), example.start)
 				}
-			}`), 0600)
-			Expect(err).NotTo(HaveOccurred())
-			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			})
+
+			context("when an option that only watchexec supports is set", func() {
+				it("falls back to watchexec", func() {
+					for _, name := range []string{"BP_LIVE_RELOAD_IGNORE", "BP_LIVE_RELOAD_SIGNAL", "BP_LIVE_RELOAD_POLL"} {
+						value := map[string]string{"BP_LIVE_RELOAD_IGNORE": "dist", "BP_LIVE_RELOAD_SIGNAL": "SIGINT", "BP_LIVE_RELOAD_POLL": "true"}[name]
+						t.Setenv(name, value)
+
+						Expect(buildProcesses()[0].Command[0]).To(Equal("watchexec"), name)
+						Expect(buffer.String()).To(ContainSubstring("Using watchexec for live reload, since node --watch does not support "+name), name)
+
+						Expect(os.Unsetenv(name)).To(Succeed())
+					}
+				})
+			})
+
+			context("when an option that only watchexec supports is turned off", func() {
+				it("keeps reloading with node --watch", func() {
+					for _, name := range []string{"BP_LIVE_RELOAD_POLL", "BP_LIVE_RELOAD_INSTALL"} {
+						t.Setenv(name, "false")
+
+						Expect(buildProcesses()[0].Command[0]).To(Equal("node"), name)
+						Expect(buffer.String()).NotTo(ContainSubstring("Using watchexec for live reload"), name)
+
+						Expect(os.Unsetenv(name)).To(Succeed())
+					}
+				})
+			})
 		})
 
-		it("returns a result with a valid start command", func() {
-			result, err := build(packit.BuildContext{
-				WorkingDir: workingDir,
-				CNBPath:    cnbDir,
-				Stack:      "some-stack",
-				BuildpackInfo: packit.BuildpackInfo{
-					Name:    "Some Buildpack",
-					Version: "some-version",
-				},
-				Plan: packit.BuildpackPlan{
-					Entries: []packit.BuildpackPlanEntry{},
-				},
-				Layers: packit.Layers{Path: layersDir},
+		context("when the watchexec options are configured", func() {
+			it.Before(func() {
+				t.Setenv("BP_LIVE_RELOAD_SIGNAL", "term")
+				t.Setenv("BP_LIVE_RELOAD_STOP_TIMEOUT", "5s")
+				t.Setenv("BP_LIVE_RELOAD_DEBOUNCE", "500ms")
+				t.Setenv("BP_LIVE_RELOAD_POLL", "true")
 			})
-			Expect(err).NotTo(HaveOccurred())
 
-			Expect(result).To(Equal(packit.BuildResult{
-				Launch: packit.LaunchMetadata{
-					Processes: []packit.Process{
-						{
-							Type:    "web",
-							Command: "bash",
-							Args: []string{
-								"-c",
-								fmt.Sprintf("cd %s/some-project-dir && some-start-command && some-poststart-command", workingDir),
-							},
-							Default: true,
-							Direct:  true,
-						},
-					}},
-			}))
+			it("passes them to watchexec", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.Processes[0].Args[:12]).To(Equal([]string{
+					"--restart",
+					"--shell", "none",
+					"--stop-signal", "SIGTERM",
+					"--stop-timeout", "5000ms",
+					"--debounce", "500ms",
+					"--poll",
+					"--watch", filepath.Join(workingDir, "some-project-dir"),
+				}))
+			})
+
+			context("when a poll interval is set", func() {
+				it.Before(func() {
+					Expect(os.Unsetenv("BP_LIVE_RELOAD_POLL")).To(Succeed())
+					t.Setenv("BP_LIVE_RELOAD_POLL_INTERVAL", "1.5s")
+				})
+
+				it("polls at that interval", func() {
+					result, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						CNBPath:    cnbDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Launch.Processes[0].Args).To(ContainElements("--poll", "1500ms"))
+				})
+			})
+
+			it("returns helpful errors for invalid values", func() {
+				for _, example := range []struct {
+					name  string
+					value string
+					err   string
+				}{
+					{name: "BP_LIVE_RELOAD_SIGNAL", value: "SIGSTOP", err: "failed to parse BP_LIVE_RELOAD_SIGNAL value SIGSTOP: expected one of SIGHUP, SIGINT, SIGQUIT, SIGKILL, SIGTERM, SIGUSR1, SIGUSR2"},
+					{name: "BP_LIVE_RELOAD_STOP_TIMEOUT", value: "5", err: `failed to parse BP_LIVE_RELOAD_STOP_TIMEOUT value 5: time: missing unit in duration "5"`},
+					{name: "BP_LIVE_RELOAD_DEBOUNCE", value: "-1s", err: "failed to parse BP_LIVE_RELOAD_DEBOUNCE value -1s: expected a duration of at least 1ms, such as 500ms or 2s"},
+					{name: "BP_LIVE_RELOAD_POLL", value: "sometimes", err: `failed to parse BP_LIVE_RELOAD_POLL value sometimes: strconv.ParseBool: parsing "sometimes": invalid syntax`},
+				} {
+					t.Setenv(example.name, example.value)
+
+					_, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						CNBPath:    cnbDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).To(MatchError(example.err), example.name)
+
+					Expect(os.Unsetenv(example.name)).To(Succeed())
+				}
+			})
 		})
-	})
 
-	context("when the package.json does not include a poststart command", func() {
-		it.Before(func() {
-			err := os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
-				"scripts": {
-					"prestart": "some-prestart-command",
-					"start": "some-start-command"
+		context("when the live reload patterns are configured", func() {
+			it.Before(func() {
+				projectPath := filepath.Join(workingDir, "some-project-dir")
+				for _, dir := range []string{"src", "config"} {
+					Expect(os.Mkdir(filepath.Join(projectPath, dir), os.ModePerm)).To(Succeed())
 				}
-			}`), 0600)
-			Expect(err).NotTo(HaveOccurred())
-			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+				Expect(os.WriteFile(filepath.Join(projectPath, "config", "app.json"), nil, 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(projectPath, ".gitignore"), []byte("dist\n"), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(projectPath, ".watchexecignore"), []byte("*.tmp\n"), 0600)).To(Succeed())
+
+				t.Setenv("BP_LIVE_RELOAD_WATCH", "src:config/*.json")
+				t.Setenv("BP_LIVE_RELOAD_IGNORE", "coverage/:*.log:/src/generated")
+			})
+
+			it("watches and ignores the configured patterns along with the ignore files", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				projectPath := filepath.Join(workingDir, "some-project-dir")
+				Expect(result.Launch.Processes[0].Args).To(Equal([]string{
+					"--restart",
+					"--shell", "none",
+					"--watch", filepath.Join(projectPath, "src"),
+					"--watch", filepath.Join(projectPath, "config", "app.json"),
+					"--ignore", filepath.Join(projectPath, "package.json"),
+					"--ignore", filepath.Join(projectPath, "yarn.lock"),
+					"--ignore", filepath.Join(projectPath, "node_modules"),
+					"--ignore", filepath.Join(projectPath, "**", "node_modules"),
+					"--ignore", filepath.Join(projectPath, ".git"),
+					"--ignore", filepath.Join(projectPath, "**", "coverage"),
+					"--ignore", filepath.Join(projectPath, "**", "*.log"),
+					"--ignore", filepath.Join(projectPath, "src", "generated"),
+					"--ignore-file", filepath.Join(projectPath, ".gitignore"),
+					"--ignore-file", filepath.Join(projectPath, ".watchexecignore"),
+					"--",
+					"bash", "-c",
+					fmt.Sprintf("cd %s && some-prestart-command && some-start-command && exec some-poststart-command", projectPath),
+				}))
+			})
+
+			context("when a watch pattern matches nothing", func() {
+				it.Before(func() {
+					t.Setenv("BP_LIVE_RELOAD_WATCH", "lib")
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						CNBPath:    cnbDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).To(MatchError(fmt.Sprintf("failed to parse BP_LIVE_RELOAD_WATCH value lib: %s matches no files", filepath.Join(workingDir, "some-project-dir", "lib"))))
+				})
+			})
 		})
 
-		it("returns a result with a valid start command", func() {
-			result, err := build(packit.BuildContext{
-				WorkingDir: workingDir,
-				CNBPath:    cnbDir,
-				Stack:      "some-stack",
-				BuildpackInfo: packit.BuildpackInfo{
-					Name:    "Some Buildpack",
-					Version: "some-version",
-				},
-				Plan: packit.BuildpackPlan{
-					Entries: []packit.BuildpackPlanEntry{},
-				},
-				Layers: packit.Layers{Path: layersDir},
+		context("when BP_LIVE_RELOAD_INSTALL=true in the build environment", func() {
+			var projectPath string
+
+			it.Before(func() {
+				projectPath = filepath.Join(workingDir, "some-project-dir")
+				Expect(os.WriteFile(filepath.Join(projectPath, "yarn.lock"), []byte("# some-lockfile\n"), 0600)).To(Succeed())
+
+				t.Setenv("BP_LIVE_RELOAD_INSTALL", "true")
 			})
-			Expect(err).NotTo(HaveOccurred())
 
-			Expect(result).To(Equal(packit.BuildResult{
-				Launch: packit.LaunchMetadata{
-					Processes: []packit.Process{
-						{
-							Type:    "web",
-							Command: "bash",
-							Args: []string{
-								"-c",
-								fmt.Sprintf("cd %s/some-project-dir && some-prestart-command && some-start-command", workingDir),
-							},
-							Default: true,
-							Direct:  true,
-						},
-					},
-				},
-			}))
+			it("watches the package manager files and reinstalls the dependencies before restarting", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				scriptPath := filepath.Join(layersDir, "live-reload-install", "bin", "reinstall")
+				Expect(result.Launch.Processes[0].Args).To(Equal([]string{
+					"--restart",
+					"--shell", "none",
+					"--watch", projectPath,
+					"--ignore", filepath.Join(projectPath, "node_modules"),
+					"--ignore", filepath.Join(projectPath, "**", "node_modules"),
+					"--ignore", filepath.Join(projectPath, ".git"),
+					"--",
+					scriptPath,
+					"bash", "-c",
+					fmt.Sprintf("cd %s && some-prestart-command && some-start-command && exec some-poststart-command", projectPath),
+				}))
+
+				Expect(result.Layers).To(HaveLen(2))
+				layer := result.Layers[1]
+				Expect(layer.Name).To(Equal("live-reload-install"))
+				Expect(layer.Launch).To(BeTrue())
+
+				snapshot, err := os.ReadFile(filepath.Join(layer.Path, "manifests"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(snapshot)).To(HavePrefix("{"))
+				Expect(string(snapshot)).To(HaveSuffix("}\n# some-lockfile\n"))
+
+				script, err := os.ReadFile(scriptPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(script)).To(ContainSubstring(fmt.Sprintf("(cd %s && { yarn install --offline || yarn install --prefer-offline; })", projectPath)))
+
+				Expect(buffer.String()).To(ContainSubstring("Reinstalling the dependencies on live reload whenever package.json or yarn.lock change"))
+			})
+
+			it("reinstalls only once the package manager files differ from the ones of the build", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				binDir := t.TempDir()
+				Expect(os.WriteFile(filepath.Join(binDir, "yarn"), []byte("#!/usr/bin/env bash\necho \"yarn $*\" >> install.log\n"), 0755)).To(Succeed())
+				t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+				run := func() string {
+					output, err := exec.Command(filepath.Join(layersDir, "live-reload-install", "bin", "reinstall"), "echo", "started").CombinedOutput()
+					Expect(err).NotTo(HaveOccurred(), string(output))
+					return string(output)
+				}
+
+				Expect(run()).To(Equal("started\n"))
+				Expect(filepath.Join(projectPath, "install.log")).NotTo(BeAnExistingFile())
+
+				Expect(os.WriteFile(filepath.Join(projectPath, "yarn.lock"), []byte("# some-other-lockfile\n"), 0600)).To(Succeed())
+				Expect(run()).To(ContainSubstring("Reinstalling the dependencies, since package.json or yarn.lock changed"))
+
+				log, err := os.ReadFile(filepath.Join(projectPath, "install.log"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(log)).To(Equal("yarn install --offline\n"))
+
+				Expect(run()).To(Equal("started\n"))
+			})
+
+			it("starts the app anyway when the reinstall fails and retries it on the next change", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				binDir := t.TempDir()
+				Expect(os.WriteFile(filepath.Join(binDir, "yarn"), []byte("#!/usr/bin/env bash\necho \"yarn $*\" >> install.log\nexit 1\n"), 0755)).To(Succeed())
+				t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+				run := func() string {
+					output, err := exec.Command(filepath.Join(layersDir, "live-reload-install", "bin", "reinstall"), "echo", "started").CombinedOutput()
+					Expect(err).NotTo(HaveOccurred(), string(output))
+					return string(output)
+				}
+
+				Expect(os.WriteFile(filepath.Join(projectPath, "yarn.lock"), []byte("# some-other-lockfile\n"), 0600)).To(Succeed())
+				output := run()
+				Expect(output).To(ContainSubstring("Failed to reinstall the dependencies, starting the app anyway"))
+				Expect(output).To(HaveSuffix("started\n"))
+
+				run()
+				log, err := os.ReadFile(filepath.Join(projectPath, "install.log"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(log)).To(Equal(strings.Repeat("yarn install --offline\nyarn install --prefer-offline\n", 2)))
+			})
+
+			context("when the project uses Yarn Berry", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(projectPath, ".yarnrc.yml"), []byte("nodeLinker: node-modules\n"), 0600)).To(Succeed())
+				})
+
+				it("reinstalls from the cache that Yarn prefers anyway", func() {
+					_, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						CNBPath:    cnbDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					script, err := os.ReadFile(filepath.Join(layersDir, "live-reload-install", "bin", "reinstall"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(script)).To(ContainSubstring(fmt.Sprintf("(cd %s && { yarn install; })", projectPath)))
+				})
+			})
+
+			context("when BP_LIVE_RELOAD_ENGINE=node", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(projectPath, "package.json"), []byte(`{
+						"scripts": {
+							"start": "node server.js"
+						}
+					}`), 0600)).To(Succeed())
+
+					t.Setenv("BP_LIVE_RELOAD_ENGINE", "node")
+				})
+
+				it("falls back to watchexec, which runs the reinstall", func() {
+					result, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						CNBPath:    cnbDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Launch.Processes[0].Command).To(Equal("watchexec"))
+					Expect(buffer.String()).To(ContainSubstring("Using watchexec for live reload, since node --watch does not support BP_LIVE_RELOAD_INSTALL"))
+				})
+			})
 		})
 	})
 
-	context("when the package.json does not include a start command", func() {
+	context("when the buildpack API supports process working directories", func() {
 		it.Before(func() {
-			err := os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
-				"scripts": {
-					"prestart": "some-prestart-command",
-					"poststart": "some-poststart-command"
-				}
-			}`), 0600)
-			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.8"`), 0600)).To(Succeed())
 			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
 		})
 
-		it("returns a result with a valid start command", func() {
+		it("sets the working directory of the process instead of changing into the project path", func() {
 			result, err := build(packit.BuildContext{
 				WorkingDir: workingDir,
 				CNBPath:    cnbDir,
-				Stack:      "some-stack",
-				BuildpackInfo: packit.BuildpackInfo{
-					Name:    "Some Buildpack",
-					Version: "some-version",
-				},
-				Plan: packit.BuildpackPlan{
-					Entries: []packit.BuildpackPlanEntry{},
-				},
-				Layers: packit.Layers{Path: layersDir},
+				Layers:     packit.Layers{Path: layersDir},
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(result).To(Equal(packit.BuildResult{
-				Launch: packit.LaunchMetadata{
-					Processes: []packit.Process{
-						{
-							Type:    "web",
-							Command: "bash",
-							Args: []string{
-								"-c",
-								fmt.Sprintf("cd %[1]s/some-project-dir && some-prestart-command && node %[1]s/server.js && some-poststart-command", workingDir),
-							},
-							Default: true,
-							Direct:  true,
+			Expect(result.Launch).To(Equal(packit.LaunchMetadata{
+				Processes: []packit.Process{
+					{
+						Type:    "web",
+						Command: "bash",
+						Args: []string{
+							"-c",
+							"some-prestart-command && some-start-command && exec some-poststart-command",
 						},
+						Default:          true,
+						Direct:           true,
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir"),
 					},
 				},
 			}))
 		})
 	})
 
-	context("when the project-path env var is not set", func() {
+	context("when the buildpack API supports direct processes", func() {
 		it.Before(func() {
-			err := os.WriteFile(filepath.Join(workingDir, "package.json"), []byte(`{
-				"scripts": {
-					"prestart": "some-prestart-command",
-					"start": "some-start-command",
-					"poststart": "some-poststart-command"
-				}
-			}`), 0600)
-			Expect(err).NotTo(HaveOccurred())
-		})
-
-		it.After(func() {
-			Expect(os.Remove(filepath.Join(workingDir, "package.json"))).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.10"`), 0600)).To(Succeed())
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
 		})
 
-		it("returns a result with a valid start command", func() {
+		it("sets the working directory of the process instead of changing into the project path", func() {
 			result, err := build(packit.BuildContext{
 				WorkingDir: workingDir,
 				CNBPath:    cnbDir,
@@ -325,34 +600,32 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(result).To(Equal(packit.BuildResult{
-				Launch: packit.LaunchMetadata{
-					Processes: []packit.Process{
-						{
-							Type:    "web",
-							Command: "bash",
-							Args: []string{
-								"-c",
-								"some-prestart-command && some-start-command && some-poststart-command",
-							},
-							Default: true,
-							Direct:  true,
+			Expect(result.Launch).To(Equal(packit.LaunchMetadata{
+				DirectProcesses: []packit.DirectProcess{
+					{
+						Type: "web",
+						Command: []string{
+							"bash", "-c",
+							"some-prestart-command && some-start-command && exec some-poststart-command",
 						},
+						Default:          true,
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir"),
 					},
 				},
 			}))
 		})
-	})
 
-	context("failure cases", func() {
-		context("when package.json does not exist", func() {
+		context("when the start script is a simple command", func() {
 			it.Before(func() {
-				Expect(os.RemoveAll(filepath.Join(workingDir, "some-project-dir", "package.json"))).To(Succeed())
-				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"scripts": {
+						"start": "node server.js"
+					}
+				}`), 0600)).To(Succeed())
 			})
 
-			it("fails with the appropriate error", func() {
-				_, err := build(packit.BuildContext{
+			it("returns an exec-form start command that runs in the project path", func() {
+				result, err := build(packit.BuildContext{
 					WorkingDir: workingDir,
 					CNBPath:    cnbDir,
 					Stack:      "some-stack",
@@ -365,17 +638,2454 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					},
 					Layers: packit.Layers{Path: layersDir},
 				})
-				Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("open %s: no such file or directory", filepath.Join(workingDir, "some-project-dir", "package.json")))))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.DirectProcesses).To(Equal([]packit.DirectProcess{
+					{
+						Type:             "web",
+						Command:          []string{"node", "server.js"},
+						Default:          true,
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir"),
+					},
+				}))
+
+				Expect(buffer.String()).To(ContainSubstring("web (default): node server.js"))
 			})
 		})
 
-		context("when package.json is malformed", func() {
+		context("and BP_LIVE_RELOAD_ENABLED=true in the build environment", func() {
 			it.Before(func() {
-				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte("%%%"), os.ModePerm)).To(Succeed())
-				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+				t.Setenv("BP_LIVE_RELOAD_ENABLED", "true")
 			})
 
-			it("fails with the appropriate error", func() {
+			it("sets the working directory of every process", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.DirectProcesses).To(Equal([]packit.DirectProcess{
+					{
+						Type: "web",
+						Command: []string{
+							"watchexec",
+							"--restart",
+							"--shell", "none",
+							"--watch", filepath.Join(workingDir, "some-project-dir"),
+							"--ignore", filepath.Join(workingDir, "some-project-dir", "package.json"),
+							"--ignore", filepath.Join(workingDir, "some-project-dir", "yarn.lock"),
+							"--ignore", filepath.Join(workingDir, "some-project-dir", "node_modules"),
+							"--ignore", filepath.Join(workingDir, "some-project-dir", "**", "node_modules"),
+							"--ignore", filepath.Join(workingDir, "some-project-dir", ".git"),
+							"--",
+							"bash", "-c",
+							"some-prestart-command && some-start-command && exec some-poststart-command",
+						},
+						Default:          true,
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir"),
+					},
+					{
+						Type: "no-reload",
+						Command: []string{
+							"bash", "-c",
+							"some-prestart-command && some-start-command && exec some-poststart-command",
+						},
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir"),
+					},
+				}))
+			})
+		})
+	})
+
+	context("when BP_YARN_START_SCRIPT is set", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+				"scripts": {
+					"prestart": "some-prestart-command",
+					"start": "some-start-command",
+					"poststart": "some-poststart-command",
+					"preserve": "some-preserve-command",
+					"serve": "some-serve-command",
+					"postserve": "some-postserve-command"
+				}
+			}`), 0600)).To(Succeed())
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			t.Setenv("BP_YARN_START_SCRIPT", "serve")
+		})
+
+		it("runs the named script along with its pre and post scripts", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Stack:      "some-stack",
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{},
+				},
+				Layers: packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Launch.Processes).To(Equal([]packit.Process{
+				{
+					Type:    "web",
+					Command: "bash",
+					Args: []string{
+						"-c",
+						fmt.Sprintf("cd %s/some-project-dir && some-preserve-command && some-serve-command && exec some-postserve-command", workingDir),
+					},
+					Default: true,
+					Direct:  true,
+				},
+			}))
+		})
+
+		context("and the named script does not exist", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"scripts": {
+						"start": "some-start-command"
+					}
+				}`), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "index.js"), nil, 0600)).To(Succeed())
+			})
+
+			it("does not fall back to the entrypoint", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(`no "serve" script in package.json`))
+			})
+		})
+	})
+
+	context("when BP_YARN_START_WORKSPACE is set", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+				"private": true,
+				"workspaces": ["packages/*", "apps/**", "!apps/legacy"]
+			}`), 0600)).To(Succeed())
+
+			for name, dir := range map[string]string{
+				"@acme/api":    filepath.Join("packages", "api"),
+				"@acme/web":    filepath.Join("apps", "frontend", "web"),
+				"@acme/legacy": filepath.Join("apps", "legacy"),
+			} {
+				Expect(os.MkdirAll(filepath.Join(workingDir, "some-project-dir", dir), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", dir, "package.json"), []byte(fmt.Sprintf(`{
+					"name": %q,
+					"scripts": {
+						"prestart": "some-prestart-command",
+						"start": "some-start-command"
+					}
+				}`, name)), 0600)).To(Succeed())
+			}
+
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			t.Setenv("BP_YARN_START_WORKSPACE", "@acme/web")
+		})
+
+		it("runs the start script of the workspace in its directory", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Stack:      "some-stack",
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{},
+				},
+				Layers: packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Launch.Processes).To(Equal([]packit.Process{
+				{
+					Type:    "web",
+					Command: "bash",
+					Args: []string{
+						"-c",
+						fmt.Sprintf("cd %s/some-project-dir/apps/frontend/web && some-prestart-command && exec some-start-command", workingDir),
+					},
+					Default: true,
+					Direct:  true,
+				},
+			}))
+
+			Expect(buffer.String()).To(ContainSubstring("Starting workspace @acme/web in apps/frontend/web"))
+		})
+
+		context("when the buildpack API supports direct processes", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.10"`), 0600)).To(Succeed())
+				t.Setenv("BP_YARN_START_WORKSPACE", "@acme/api")
+			})
+
+			it("sets the working directory of the process to the workspace", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.DirectProcesses).To(Equal([]packit.DirectProcess{
+					{
+						Type: "web",
+						Command: []string{
+							"bash", "-c",
+							"some-prestart-command && exec some-start-command",
+						},
+						Default:          true,
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir", "packages", "api"),
+					},
+				}))
+			})
+		})
+
+		context("when the workspaces field is an object", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"workspaces": {
+						"packages": ["packages/*"],
+						"nohoist": ["**/react-native"]
+					}
+				}`), 0600)).To(Succeed())
+				t.Setenv("BP_YARN_START_WORKSPACE", "@acme/api")
+			})
+
+			it("reads the packages patterns", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.Processes).To(HaveLen(1))
+				Expect(result.Launch.Processes[0].Args).To(Equal([]string{
+					"-c",
+					fmt.Sprintf("cd %s/some-project-dir/packages/api && some-prestart-command && exec some-start-command", workingDir),
+				}))
+			})
+		})
+
+		context("when the workspace is excluded by a negated pattern", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_WORKSPACE", "@acme/legacy")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(fmt.Sprintf(`no workspace named "@acme/legacy" found in %s`, filepath.Join(workingDir, "some-project-dir"))))
+			})
+		})
+	})
+
+	context("when BP_YARN_START_WORKSPACES is true", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+				"private": true,
+				"workspaces": ["services/*", "libs/*"]
+			}`), 0600)).To(Succeed())
+
+			for dir, content := range map[string]string{
+				filepath.Join("services", "api"): `{
+					"name": "@acme/api",
+					"scripts": {
+						"start": "node api.js"
+					}
+				}`,
+				filepath.Join("services", "queue"): `{
+					"name": "@acme/queue~worker",
+					"scripts": {
+						"prestart": "some-prestart-command",
+						"start": "node worker.js"
+					}
+				}`,
+				filepath.Join("libs", "config"): `{
+					"name": "@acme/config",
+					"main": "index.js"
+				}`,
+			} {
+				Expect(os.MkdirAll(filepath.Join(workingDir, "some-project-dir", dir), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", dir, "package.json"), []byte(content), 0600)).To(Succeed())
+			}
+
+			Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.10"`), 0600)).To(Succeed())
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			t.Setenv("BP_YARN_START_WORKSPACES", "true")
+		})
+
+		it("adds a process for each workspace with a start script and makes the first the default", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Stack:      "some-stack",
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{},
+				},
+				Layers: packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Launch.DirectProcesses).To(Equal([]packit.DirectProcess{
+				{
+					Type:             "api",
+					Command:          []string{"node", "api.js"},
+					Default:          true,
+					WorkingDirectory: filepath.Join(workingDir, "some-project-dir", "services", "api"),
+				},
+				{
+					Type:             "queue-worker",
+					Command:          []string{"bash", "-c", "some-prestart-command && exec node worker.js"},
+					WorkingDirectory: filepath.Join(workingDir, "some-project-dir", "services", "queue"),
+				},
+			}))
+
+			Expect(buffer.String()).To(ContainSubstring("Adding processes for workspaces"))
+			Expect(buffer.String()).To(ContainSubstring("api: services/api"))
+			Expect(buffer.String()).To(ContainSubstring("queue-worker: services/queue"))
+		})
+
+		context("when BP_YARN_START_DEFAULT_PROCESS is set", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_DEFAULT_PROCESS", "queue-worker")
+			})
+
+			it("makes that process the default", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.DirectProcesses).To(HaveLen(2))
+				Expect(result.Launch.DirectProcesses[0].Default).To(BeFalse())
+				Expect(result.Launch.DirectProcesses[1].Type).To(Equal("queue-worker"))
+				Expect(result.Launch.DirectProcesses[1].Default).To(BeTrue())
+			})
+		})
+
+		context("when live reload and debugging are enabled", func() {
+			it.Before(func() {
+				t.Setenv("BP_LIVE_RELOAD_ENABLED", "true")
+				t.Setenv("BP_DEBUG_ENABLED", "true")
+			})
+
+			it("ignores them, since there is no web process", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.DirectProcesses).To(HaveLen(2))
+				Expect(result.Launch.DirectProcesses[0].Type).To(Equal("api"))
+				Expect(result.Launch.DirectProcesses[1].Type).To(Equal("queue-worker"))
+
+				Expect(buffer.String()).To(ContainSubstring(`Ignoring BP_LIVE_RELOAD_ENABLED, since there is no "start" script for the web process`))
+				Expect(buffer.String()).To(ContainSubstring(`Ignoring BP_DEBUG_ENABLED, since there is no "start" script for the web process`))
+			})
+		})
+
+		context("when the project has a start script of its own", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"workspaces": ["services/*"],
+					"scripts": {
+						"start": "node gateway.js"
+					}
+				}`), 0600)).To(Succeed())
+			})
+
+			it("keeps the web process as the default", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.DirectProcesses).To(Equal([]packit.DirectProcess{
+					{
+						Type:             "web",
+						Command:          []string{"node", "gateway.js"},
+						Default:          true,
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir"),
+					},
+					{
+						Type:             "api",
+						Command:          []string{"node", "api.js"},
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir", "services", "api"),
+					},
+					{
+						Type:             "queue-worker",
+						Command:          []string{"bash", "-c", "some-prestart-command && exec node worker.js"},
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir", "services", "queue"),
+					},
+				}))
+			})
+		})
+
+		context("when a workspace process type is already in use", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_PROCESSES", "api=start")
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"workspaces": ["services/*"],
+					"scripts": {
+						"start": "node gateway.js"
+					}
+				}`), 0600)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(`failed to add a process for workspace @acme/api: process type "api" is already in use`))
+			})
+		})
+
+		context("when BP_YARN_START_DEFAULT_PROCESS names a process that does not exist", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_DEFAULT_PROCESS", "config")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(`failed to parse BP_YARN_START_DEFAULT_PROCESS value config: no "config" process`))
+			})
+		})
+
+		context("when BP_YARN_START_WORKSPACES is set to an invalid value", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_WORKSPACES", "not-a-bool")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to parse BP_YARN_START_WORKSPACES value not-a-bool")))
+			})
+		})
+	})
+
+	context("when BP_YARN_START_PROCESSES is set", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+				"scripts": {
+					"start": "some-start-command",
+					"prequeue:work": "some-prequeue-command",
+					"queue:work": "some-queue-command",
+					"db:migrate": "some-migrate-command --all"
+				}
+			}`), 0600)).To(Succeed())
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			t.Setenv("BP_YARN_START_PROCESSES", "worker=queue:work, migrate = db:migrate")
+		})
+
+		it("adds a process for each named script", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Stack:      "some-stack",
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{},
+				},
+				Layers: packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Launch.Processes).To(Equal([]packit.Process{
+				{
+					Type:    "web",
+					Command: "bash",
+					Args: []string{
+						"-c",
+						fmt.Sprintf("cd %s/some-project-dir && exec some-start-command", workingDir),
+					},
+					Default: true,
+					Direct:  true,
+				},
+				{
+					Type:    "worker",
+					Command: "bash",
+					Args: []string{
+						"-c",
+						fmt.Sprintf("cd %s/some-project-dir && some-prequeue-command && exec some-queue-command", workingDir),
+					},
+					Direct: true,
+				},
+				{
+					Type:    "migrate",
+					Command: "bash",
+					Args: []string{
+						"-c",
+						fmt.Sprintf("cd %s/some-project-dir && exec some-migrate-command --all", workingDir),
+					},
+					Direct: true,
+				},
+			}))
+		})
+
+		context("when the buildpack API supports direct processes", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.10"`), 0600)).To(Succeed())
+			})
+
+			it("runs the additional processes in the project path", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.DirectProcesses).To(Equal([]packit.DirectProcess{
+					{
+						Type:             "web",
+						Command:          []string{"some-start-command"},
+						Default:          true,
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir"),
+					},
+					{
+						Type:             "worker",
+						Command:          []string{"bash", "-c", "some-prequeue-command && exec some-queue-command"},
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir"),
+					},
+					{
+						Type:             "migrate",
+						Command:          []string{"some-migrate-command", "--all"},
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir"),
+					},
+				}))
+			})
+		})
+	})
+
+	context("when the project uses Yarn Plug'n'Play", func() {
+		it.Before(func() {
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", ".yarnrc.yml"), []byte("nodeLinker: pnp\n"), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", ".pnp.cjs"), nil, 0600)).To(Succeed())
+		})
+
+		it("injects the Plug'n'Play runtime into NODE_OPTIONS at launch", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Stack:      "some-stack",
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{},
+				},
+				Layers: packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(1))
+			layer := result.Layers[0]
+			Expect(layer.Name).To(Equal("launch-env"))
+			Expect(layer.Path).To(Equal(filepath.Join(layersDir, "launch-env")))
+			Expect(layer.Launch).To(BeTrue())
+			Expect(layer.LaunchEnv).To(Equal(packit.Environment{
+				"NODE_OPTIONS.prepend": fmt.Sprintf("--require %s/some-project-dir/.pnp.cjs", workingDir),
+				"NODE_OPTIONS.delim":   " ",
+			}))
+
+			Expect(buffer.String()).To(ContainSubstring("Configuring Yarn Plug'n'Play module resolution"))
+		})
+
+		context("when there is a Plug'n'Play ES module loader", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", ".pnp.loader.mjs"), nil, 0600)).To(Succeed())
+			})
+
+			it("also injects the loader", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[0].LaunchEnv).To(Equal(packit.Environment{
+					"NODE_OPTIONS.prepend": fmt.Sprintf("--require %[1]s/some-project-dir/.pnp.cjs --experimental-loader file://%[1]s/some-project-dir/.pnp.loader.mjs", workingDir),
+					"NODE_OPTIONS.delim":   " ",
+				}))
+			})
+		})
+
+		context("when the project path contains spaces", func() {
+			it.Before(func() {
+				Expect(os.Rename(filepath.Join(workingDir, "some-project-dir"), filepath.Join(workingDir, "some project dir"))).To(Succeed())
+				t.Setenv("BP_NODE_PROJECT_PATH", "some project dir")
+			})
+
+			it("quotes the runtime path", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[0].LaunchEnv).To(HaveKeyWithValue("NODE_OPTIONS.prepend", fmt.Sprintf(`--require "%s/some project dir/.pnp.cjs"`, workingDir)))
+			})
+		})
+	})
+
+	context("when the project is a member of a Plug'n'Play workspaces project", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "package.json"), []byte(`{
+				"workspaces": ["packages/*"]
+			}`), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "yarn.lock"), nil, 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, ".yarnrc.yml"), []byte("nodeLinker: pnp\n"), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, ".pnp.cjs"), nil, 0600)).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(workingDir, "packages", "api"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "packages", "api", "package.json"), []byte(`{
+				"scripts": {
+					"start": "node server.js"
+				}
+			}`), 0600)).To(Succeed())
+
+			t.Setenv("BP_NODE_PROJECT_PATH", filepath.Join("packages", "api"))
+		})
+
+		it("uses the Plug'n'Play runtime of the workspace root", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Stack:      "some-stack",
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{},
+				},
+				Layers: packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(1))
+			Expect(result.Layers[0].LaunchEnv).To(HaveKeyWithValue("NODE_OPTIONS.prepend", fmt.Sprintf("--require %s/.pnp.cjs", workingDir)))
+			Expect(result.Launch.Processes[0].Args).To(Equal([]string{
+				"-c",
+				fmt.Sprintf("cd %s/packages/api && exec node server.js", workingDir),
+			}))
+		})
+	})
+
+	context("when setting up the script environment of the processes", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+				"name": "@acme/some-app",
+				"version": "1.2.3",
+				"config": {
+					"port": 8080,
+					"debug": false,
+					"log-level": "info",
+					"db": {
+						"host": "localhost",
+						"replicas": ["a", "b"]
+					},
+					"unset": null
+				},
+				"scripts": {
+					"start": "node server.js",
+					"queue:work": "node worker.js --queue default"
+				}
+			}`), 0600)).To(Succeed())
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			t.Setenv("BP_YARN_START_PROCESSES", "worker=queue:work")
+		})
+
+		it("sets the variables that yarn would set for each process", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Stack:      "some-stack",
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{},
+				},
+				Layers: packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(1))
+			layer := result.Layers[0]
+			Expect(layer.Name).To(Equal("launch-env"))
+			Expect(layer.LaunchEnv).To(BeEmpty())
+
+			packageEnv := packit.Environment{
+				"npm_package_name.override":                 "@acme/some-app",
+				"npm_package_version.override":              "1.2.3",
+				"npm_package_config_port.override":          "8080",
+				"npm_package_config_debug.override":         "false",
+				"npm_package_config_log_level.override":     "info",
+				"npm_package_config_db_host.override":       "localhost",
+				"npm_package_config_db_replicas_0.override": "a",
+				"npm_package_config_db_replicas_1.override": "b",
+			}
+
+			webEnv := packit.Environment{
+				"npm_lifecycle_event.override":  "start",
+				"npm_lifecycle_script.override": "node server.js",
+				"PATH.prepend":                  filepath.Join(workingDir, "some-project-dir", "node_modules", ".bin"),
+				"PATH.delim":                    ":",
+			}
+			workerEnv := packit.Environment{
+				"npm_lifecycle_event.override":  "queue:work",
+				"npm_lifecycle_script.override": "node worker.js --queue default",
+				"PATH.prepend":                  filepath.Join(workingDir, "some-project-dir", "node_modules", ".bin"),
+				"PATH.delim":                    ":",
+			}
+			for key, value := range packageEnv {
+				webEnv[key] = value
+				workerEnv[key] = value
+			}
+
+			Expect(layer.ProcessLaunchEnv).To(Equal(map[string]packit.Environment{
+				"web":    webEnv,
+				"worker": workerEnv,
+			}))
+
+			Expect(buffer.String()).To(MatchRegexp(`npm_lifecycle_event\s+-> "queue:work"`))
+		})
+
+		context("when live reload is enabled", func() {
+			it.Before(func() {
+				t.Setenv("BP_LIVE_RELOAD_ENABLED", "true")
+				t.Setenv("BP_YARN_START_PROCESSES", "")
+			})
+
+			it("sets the start script variables for both web processes", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[0].ProcessLaunchEnv).To(HaveKey("web"))
+				Expect(result.Layers[0].ProcessLaunchEnv).To(HaveKey("no-reload"))
+				Expect(result.Layers[0].ProcessLaunchEnv["no-reload"]).To(HaveKeyWithValue("npm_lifecycle_event.override", "start"))
+			})
+		})
+
+		context("when the start command falls back to an entrypoint", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"main": "app.js"
+				}`), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "app.js"), nil, 0600)).To(Succeed())
+				t.Setenv("BP_YARN_START_PROCESSES", "")
+			})
+
+			it("reports the fallback command as the start script", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[0].ProcessLaunchEnv).To(Equal(map[string]packit.Environment{
+					"web": {
+						"npm_lifecycle_event.override":  "start",
+						"npm_lifecycle_script.override": "node app.js",
+						"PATH.prepend":                  filepath.Join(workingDir, "some-project-dir", "node_modules", ".bin"),
+						"PATH.delim":                    ":",
+					},
+				}))
+			})
+		})
+	})
+
+	context("when putting package executables on the PATH", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "package.json"), []byte(`{
+				"workspaces": ["packages/*"]
+			}`), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "yarn.lock"), nil, 0600)).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(workingDir, "packages", "api"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "packages", "api", "package.json"), []byte(`{
+				"name": "@acme/api",
+				"scripts": {
+					"start": "nest start"
+				}
+			}`), 0600)).To(Succeed())
+		})
+
+		context("when the project path is a workspace member", func() {
+			it.Before(func() {
+				t.Setenv("BP_NODE_PROJECT_PATH", filepath.Join("packages", "api"))
+			})
+
+			it("adds the executables of the member and of the workspace root", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[0].ProcessLaunchEnv["web"]).To(HaveKeyWithValue("PATH.prepend", fmt.Sprintf("%[1]s/packages/api/node_modules/.bin:%[1]s/node_modules/.bin", workingDir)))
+				Expect(result.Layers[0].ProcessLaunchEnv["web"]).To(HaveKeyWithValue("PATH.delim", ":"))
+
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Adding package executables to PATH\n    %[1]s/packages/api/node_modules/.bin\n    %[1]s/node_modules/.bin\n", workingDir)))
+			})
+		})
+
+		context("when the workspaces have processes of their own", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "package.json"), []byte(`{
+					"workspaces": ["packages/*"],
+					"scripts": {
+						"start": "next start"
+					}
+				}`), 0600)).To(Succeed())
+				t.Setenv("BP_YARN_START_WORKSPACES", "true")
+			})
+
+			it("adds the executables of each workspace to its process", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[0].ProcessLaunchEnv["web"]).To(HaveKeyWithValue("PATH.prepend", fmt.Sprintf("%s/node_modules/.bin", workingDir)))
+				Expect(result.Layers[0].ProcessLaunchEnv["api"]).To(HaveKeyWithValue("PATH.prepend", fmt.Sprintf("%[1]s/packages/api/node_modules/.bin:%[1]s/node_modules/.bin", workingDir)))
+			})
+		})
+
+		context("when the project uses Plug'n'Play", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, ".pnp.cjs"), nil, 0600)).To(Succeed())
+				t.Setenv("BP_NODE_PROJECT_PATH", filepath.Join("packages", "api"))
+			})
+
+			it("leaves the PATH alone", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[0].ProcessLaunchEnv["web"]).NotTo(HaveKey("PATH.prepend"))
+				Expect(buffer.String()).NotTo(ContainSubstring("Adding package executables to PATH"))
+			})
+		})
+	})
+
+	context("when the start script refers to other scripts", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.10"`), 0600)).To(Succeed())
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+		})
+
+		var buildCommand = func() []string {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Layers:     packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Launch.DirectProcesses).NotTo(BeEmpty())
+			return result.Launch.DirectProcesses[0].Command
+		}
+
+		var writeScripts = func(scripts string) {
+			Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(fmt.Sprintf(`{"scripts": %s}`, scripts)), 0600)).To(Succeed())
+		}
+
+		it("inlines the referenced scripts along with their pre and post scripts", func() {
+			for _, example := range []struct {
+				scripts  string
+				expected []string
+			}{
+				{
+					scripts:  `{"start": "yarn run serve", "serve": "node server.js"}`,
+					expected: []string{"node", "server.js"},
+				},
+				{
+					scripts:  `{"start": "yarn build:env && yarn run serve", "build:env": "node env.js", "serve": "node server.js"}`,
+					expected: []string{"bash", "-c", "node env.js && exec node server.js"},
+				},
+				{
+					scripts:  `{"start": "npm run serve", "preserve": "node migrate.js", "serve": "yarn run-server", "run-server": "node server.js", "postserve": "echo done"}`,
+					expected: []string{"bash", "-c", "node migrate.js && node server.js && exec echo done"},
+				},
+				{
+					scripts:  `{"start": "npm run-script serve", "serve": "cd dist && node server.js"}`,
+					expected: []string{"bash", "-c", "( cd dist && node server.js )"},
+				},
+				{
+					scripts:  `{"start": "yarn run serve --port 8080", "serve": "node server.js"}`,
+					expected: []string{"yarn", "run", "serve", "--port", "8080"},
+				},
+				{
+					scripts:  `{"start": "yarn install", "install": "node install.js"}`,
+					expected: []string{"yarn", "install"},
+				},
+				{
+					scripts:  `{"start": "yarn run missing || node server.js"}`,
+					expected: []string{"bash", "-c", "yarn run missing || node server.js"},
+				},
+			} {
+				writeScripts(example.scripts)
+				Expect(buildCommand()).To(Equal(example.expected), example.scripts)
+			}
+		})
+
+		it("runs a referenced script from the package directory after a change of directory", func() {
+			writeScripts(`{"start": "cd dist && yarn run serve", "serve": "node server.js"}`)
+			Expect(buildCommand()).To(Equal([]string{
+				"bash", "-c",
+				fmt.Sprintf("cd dist && ( cd %s && node server.js )", filepath.Join(workingDir, "some-project-dir")),
+			}))
+		})
+
+		context("when BP_YARN_START_INLINE_SCRIPTS is false", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_INLINE_SCRIPTS", "false")
+			})
+
+			it("leaves the references alone", func() {
+				writeScripts(`{"start": "yarn run serve", "serve": "node server.js"}`)
+				Expect(buildCommand()).To(Equal([]string{"yarn", "run", "serve"}))
+			})
+		})
+
+		context("when the references form a cycle", func() {
+			it("returns an error", func() {
+				writeScripts(`{"start": "yarn run serve", "serve": "node server.js && npm run restart", "restart": "yarn start"}`)
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to inline package.json scripts: cycle start -> serve -> restart -> start"))
+			})
+		})
+	})
+
+	context("when BP_DEBUG_ENABLED is true", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.10"`), 0600)).To(Succeed())
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			t.Setenv("BP_DEBUG_ENABLED", "true")
+		})
+
+		it("adds a debug process that enables the inspector through NODE_OPTIONS", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Layers:     packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			command := []string{"bash", "-c", "some-prestart-command && some-start-command && exec some-poststart-command"}
+			Expect(result.Launch.DirectProcesses).To(Equal([]packit.DirectProcess{
+				{
+					Type:             "web",
+					Command:          command,
+					Default:          true,
+					WorkingDirectory: filepath.Join(workingDir, "some-project-dir"),
+				},
+				{
+					Type:             "debug",
+					Command:          command,
+					WorkingDirectory: filepath.Join(workingDir, "some-project-dir"),
+				},
+			}))
+
+			preload := filepath.Join(layersDir, "debug-preload", "inspect.js")
+			env := result.Layers[0].ProcessLaunchEnv
+			Expect(env["web"]).NotTo(HaveKey("NODE_OPTIONS.prepend"))
+			Expect(env["debug"]).To(HaveKeyWithValue("NODE_OPTIONS.prepend", "--require "+preload))
+			Expect(env["debug"]).To(HaveKeyWithValue("NODE_OPTIONS.delim", " "))
+			Expect(env["debug"]).To(HaveKeyWithValue("npm_lifecycle_event.override", "start"))
+
+			Expect(result.Layers).To(HaveLen(2))
+			Expect(result.Layers[1].Name).To(Equal("debug-preload"))
+			Expect(result.Layers[1].Launch).To(BeTrue())
+			Expect(preload).To(BeARegularFile())
+
+			Expect(buffer.String()).To(ContainSubstring("Adding the debug processes with the Node.js inspector"))
+			Expect(buffer.String()).To(ContainSubstring("Expose port 9229 of the container to attach a debugger, or the port that BP_NODE_DEBUG_PORT sets at launch"))
+		})
+
+		it("opens the inspector in the node processes other than yarn", func() {
+			if _, err := exec.LookPath("node"); err != nil {
+				t.Skip("node is not installed")
+			}
+
+			_, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Layers:     packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			scriptsDir := t.TempDir()
+			for _, name := range []string{"server.js", "yarn.js", "yarn-4.1.0.cjs"} {
+				Expect(os.WriteFile(filepath.Join(scriptsDir, name), []byte("console.log(require('inspector').url() !== undefined);\n"), 0600)).To(Succeed())
+			}
+
+			inspects := func(script, port string) string {
+				command := exec.Command("node", filepath.Join(scriptsDir, script))
+				command.Env = append(os.Environ(),
+					"NODE_OPTIONS=--require "+filepath.Join(layersDir, "debug-preload", "inspect.js"),
+					"BP_NODE_DEBUG_PORT="+port,
+				)
+				output, err := command.Output()
+				Expect(err).NotTo(HaveOccurred())
+				return strings.TrimSpace(string(output))
+			}
+
+			// Port 0 lets the inspector pick any free port at launch.
+			Expect(inspects("server.js", "0")).To(Equal("true"))
+			Expect(inspects("yarn.js", "0")).To(Equal("false"))
+			Expect(inspects("yarn-4.1.0.cjs", "0")).To(Equal("false"))
+			Expect(inspects("server.js", "not-a-port")).To(Equal("false"))
+		})
+
+		context("when live reload is enabled and BP_NODE_DEBUG_PORT is set", func() {
+			it.Before(func() {
+				t.Setenv("BP_LIVE_RELOAD_ENABLED", "true")
+				t.Setenv("BP_NODE_DEBUG_PORT", "9230")
+			})
+
+			it("adds a reloadable debug process as well", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				var types []string
+				for _, process := range result.Launch.DirectProcesses {
+					types = append(types, process.Type)
+				}
+				Expect(types).To(Equal([]string{"web", "no-reload", "debug-reload", "debug"}))
+				Expect(result.Launch.DirectProcesses[2].Command[0]).To(Equal("watchexec"))
+				Expect(result.Launch.DirectProcesses[2].Default).To(BeFalse())
+
+				preload := filepath.Join(layersDir, "debug-preload", "inspect.js")
+				env := result.Layers[0].ProcessLaunchEnv
+				Expect(env["debug-reload"]).To(HaveKeyWithValue("NODE_OPTIONS.prepend", "--require "+preload))
+				Expect(env["debug"]).To(HaveKeyWithValue("NODE_OPTIONS.prepend", "--require "+preload))
+
+				content, err := os.ReadFile(preload)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring(`const port = process.env.BP_NODE_DEBUG_PORT || "9230";`))
+
+				Expect(buffer.String()).To(ContainSubstring("Adding the debug-reload and debug processes with the Node.js inspector"))
+				Expect(buffer.String()).To(ContainSubstring("Expose port 9230 of the container to attach a debugger"))
+			})
+		})
+
+		context("when the processes launch through yarn", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_MODE", "yarn")
+			})
+
+			it("preloads the inspector into the node processes that yarn runs", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Launch.DirectProcesses[1].Command).To(Equal([]string{"yarn", "run", "start"}))
+				Expect(result.Layers[0].ProcessLaunchEnv).To(Equal(map[string]packit.Environment{
+					"debug": {
+						"NODE_OPTIONS.prepend": "--require " + filepath.Join(layersDir, "debug-preload", "inspect.js"),
+						"NODE_OPTIONS.delim":   " ",
+					},
+				}))
+			})
+		})
+
+		context("when BP_NODE_DEBUG_PORT is not a port", func() {
+			it.Before(func() {
+				t.Setenv("BP_NODE_DEBUG_PORT", "70000")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to parse BP_NODE_DEBUG_PORT value 70000: expected a port number between 1 and 65535"))
+			})
+		})
+
+		context("when an additional process is called debug", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_PROCESSES", "debug=start")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(`failed to add the debug process: process type "debug" is already in use`))
+			})
+		})
+	})
+
+	context("when BP_YARN_START_MODE is set", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.10"`), 0600)).To(Succeed())
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+		})
+
+		context("to yarn", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_MODE", "yarn")
+				t.Setenv("BP_YARN_START_PROCESSES", "worker=queue:work")
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"scripts": {
+						"prestart": "some-prestart-command",
+						"start": "NODE_ENV=production some-start-command",
+						"queue:work": "yarn run worker"
+					}
+				}`), 0600)).To(Succeed())
+			})
+
+			it("runs the scripts through yarn run in the project path", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Launch.DirectProcesses).To(Equal([]packit.DirectProcess{
+					{
+						Type:             "web",
+						Command:          []string{"yarn", "run", "start"},
+						Default:          true,
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir"),
+					},
+					{
+						Type:             "worker",
+						Command:          []string{"yarn", "run", "queue:work"},
+						WorkingDirectory: filepath.Join(workingDir, "some-project-dir"),
+					},
+				}))
+				Expect(result.Layers).To(BeEmpty())
+
+				Expect(buffer.String()).To(ContainSubstring("Launching through Yarn, since BP_YARN_START_MODE is set to yarn"))
+			})
+
+			context("when there is no start script", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{}`), 0600)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "server.js"), nil, 0600)).To(Succeed())
+					t.Setenv("BP_YARN_START_PROCESSES", "")
+				})
+
+				it("runs the entrypoint through yarn node", func() {
+					result, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						CNBPath:    cnbDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Launch.DirectProcesses[0].Command).To(Equal([]string{"yarn", "node", "server.js"}))
+				})
+			})
+		})
+
+		context("to auto", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_MODE", "auto")
+			})
+
+			it("runs the scripts directly for a project without Yarn Berry", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Launch.DirectProcesses[0].Command).To(Equal([]string{
+					"bash", "-c", "some-prestart-command && some-start-command && exec some-poststart-command",
+				}))
+			})
+
+			it("runs the scripts through yarn run for a Yarn Berry project", func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", ".yarnrc.yml"), []byte("nodeLinker: node-modules\n"), 0600)).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Launch.DirectProcesses[0].Command).To(Equal([]string{"yarn", "run", "start"}))
+				Expect(buffer.String()).To(ContainSubstring("Launching through Yarn, since the project has a .yarnrc.yml"))
+			})
+
+			it("runs the scripts through yarn run when package.json declares Yarn Berry", func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"packageManager": "yarn@4.1.0",
+					"scripts": {
+						"start": "some-start-command"
+					}
+				}`), 0600)).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Launch.DirectProcesses[0].Command).To(Equal([]string{"yarn", "run", "start"}))
+				Expect(buffer.String()).To(ContainSubstring("Launching through Yarn, since package.json declares yarn@4.1.0 as its package manager"))
+			})
+		})
+
+		context("to an invalid value", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_MODE", "npx")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to parse BP_YARN_START_MODE value npx: expected one of auto, direct, yarn"))
+			})
+		})
+	})
+
+	context("when the start script runs the scripts of workspaces", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "0.10"`), 0600)).To(Succeed())
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+
+			for name, scripts := range map[string]string{
+				"@acme/api":    `{"prestart": "node migrate.js", "start": "node server.js"}`,
+				"@acme/worker": `{"start": "cd dist && node worker.js", "serve": "yarn run start"}`,
+				"@acme/docs":   `{"build": "node build.js"}`,
+			} {
+				dir := filepath.Join(workingDir, "some-project-dir", "packages", strings.TrimPrefix(name, "@acme/"))
+				Expect(os.MkdirAll(dir, os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(dir, "package.json"), []byte(fmt.Sprintf(`{"name": %q, "version": "1.0.0", "scripts": %s}`, name, scripts)), 0600)).To(Succeed())
+			}
+		})
+
+		var writeScripts = func(scripts string) {
+			Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(fmt.Sprintf(`{
+				"workspaces": ["packages/*"],
+				"scripts": %s
+			}`, scripts)), 0600)).To(Succeed())
+		}
+
+		it("runs the script of the workspace from its directory", func() {
+			writeScripts(`{"start": "yarn workspace @acme/worker serve"}`)
+
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Layers:     packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Launch.DirectProcesses).To(Equal([]packit.DirectProcess{
+				{
+					Type:             "web",
+					Command:          []string{"bash", "-c", "( cd dist && node worker.js )"},
+					Default:          true,
+					WorkingDirectory: filepath.Join(workingDir, "some-project-dir", "packages", "worker"),
+				},
+			}))
+
+			Expect(result.Layers[0].ProcessLaunchEnv["web"]).To(Equal(packit.Environment{
+				"npm_lifecycle_event.override":  "serve",
+				"npm_lifecycle_script.override": "yarn run start",
+				"npm_package_name.override":     "@acme/worker",
+				"npm_package_version.override":  "1.0.0",
+				"PATH.prepend": strings.Join([]string{
+					filepath.Join(workingDir, "some-project-dir", "packages", "worker", "node_modules", ".bin"),
+					filepath.Join(workingDir, "some-project-dir", "node_modules", ".bin"),
+				}, ":"),
+				"PATH.delim": ":",
+			}))
+
+			Expect(buffer.String()).To(ContainSubstring(`Resolving the "start" script to the "serve" script of workspace @acme/worker in packages/worker`))
+		})
+
+		it("inlines the scripts of the workspaces in a subshell of their own", func() {
+			api := filepath.Join(workingDir, "some-project-dir", "packages", "api")
+			worker := filepath.Join(workingDir, "some-project-dir", "packages", "worker")
+
+			for _, example := range []struct {
+				scripts  string
+				expected []string
+			}{
+				{
+					scripts:  `{"start": "node env.js && yarn workspace @acme/api start"}`,
+					expected: []string{"bash", "-c", fmt.Sprintf("node env.js && ( cd %s && node migrate.js && node server.js )", api)},
+				},
+				{
+					scripts:  `{"start": "yarn workspaces foreach -Av run start"}`,
+					expected: []string{"bash", "-c", fmt.Sprintf("( cd %s && node migrate.js && node server.js ) && ( cd %s && cd dist && node worker.js )", api, worker)},
+				},
+				{
+					scripts:  `{"start": "yarn workspaces foreach --parallel run start"}`,
+					expected: []string{"yarn", "workspaces", "foreach", "--parallel", "run", "start"},
+				},
+				{
+					scripts:  `{"start": "yarn workspaces run start"}`,
+					expected: []string{"yarn", "workspaces", "run", "start"},
+				},
+				{
+					scripts:  `{"start": "yarn workspace @acme/docs start"}`,
+					expected: []string{"yarn", "workspace", "@acme/docs", "start"},
+				},
+			} {
+				writeScripts(example.scripts)
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Launch.DirectProcesses[0].Command).To(Equal(example.expected), example.scripts)
+			}
+		})
+
+		context("when the scripts of the workspaces form a cycle", func() {
+			it("returns an error", func() {
+				writeScripts(`{"start": "node env.js && yarn workspace @acme/api start"}`)
+				for name, target := range map[string]string{"api": "@acme/worker", "worker": "@acme/api"} {
+					Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "packages", name, "package.json"), []byte(fmt.Sprintf(`{
+						"name": "@acme/%s",
+						"scripts": {
+							"start": "yarn workspace %s start"
+						}
+					}`, name, target)), 0600)).To(Succeed())
+				}
+
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to inline package.json scripts: cycle start -> @acme/api start -> @acme/worker start -> @acme/api start"))
+			})
+		})
+	})
+
+	context("when the project path contains shell metacharacters", func() {
+		it("quotes the project path in the start command", func() {
+			for _, projectPath := range []struct {
+				name   string
+				quoted string
+			}{
+				{name: "some dir", quoted: `'%s/some dir'`},
+				{name: "it's", quoted: `'%s/it'\''s'`},
+				{name: "$(touch pwned)", quoted: `'%s/$(touch pwned)'`},
+				{name: "`touch pwned`", quoted: "'%s/`touch pwned`'"},
+				{name: "x; touch pwned", quoted: `'%s/x; touch pwned'`},
+				{name: "x && touch pwned #", quoted: `'%s/x && touch pwned #'`},
+				{name: "$HOME", quoted: `'%s/$HOME'`},
+				{name: `back\slash`, quoted: `'%s/back\slash'`},
+				{name: "glob*", quoted: `'%s/glob*'`},
+				{name: "new\nline", quoted: "'%s/new\nline'"},
+				{name: `"quoted"`, quoted: `'%s/"quoted"'`},
+			} {
+				path := filepath.Join(workingDir, projectPath.name)
+				Expect(os.Mkdir(path, os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(path, "package.json"), []byte(`{
+					"scripts": {
+						"start": "pwd > cwd"
+					}
+				}`), 0600)).To(Succeed())
+
+				t.Setenv("BP_NODE_PROJECT_PATH", projectPath.name)
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.Processes).To(Equal([]packit.Process{
+					{
+						Type:    "web",
+						Command: "bash",
+						Args: []string{
+							"-c",
+							fmt.Sprintf("cd "+projectPath.quoted+" && pwd > cwd", workingDir),
+						},
+						Default: true,
+						Direct:  true,
+					},
+				}), projectPath.name)
+
+				cmd := exec.Command(result.Launch.Processes[0].Command, result.Launch.Processes[0].Args...)
+				cmd.Dir = workingDir
+				output, err := cmd.CombinedOutput()
+				Expect(err).NotTo(HaveOccurred(), string(output))
+
+				cwd, err := os.ReadFile(filepath.Join(path, "cwd"))
+				Expect(err).NotTo(HaveOccurred(), projectPath.name)
+				Expect(string(cwd)).To(Equal(path+"\n"), projectPath.name)
+				Expect(filepath.Join(workingDir, "pwned")).NotTo(BeAnExistingFile(), projectPath.name)
+			}
+		})
+
+		context("and BP_LIVE_RELOAD_ENABLED=true in the build environment", func() {
+			it.Before(func() {
+				t.Setenv("BP_LIVE_RELOAD_ENABLED", "true")
+				t.Setenv("BP_NODE_PROJECT_PATH", "glob*[dir]")
+
+				path := filepath.Join(workingDir, "glob*[dir]")
+				Expect(os.Mkdir(path, os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(path, "package.json"), []byte(`{
+					"scripts": {
+						"start": "node server.js"
+					}
+				}`), 0600)).To(Succeed())
+			})
+
+			it("escapes the project path in the watchexec ignore patterns", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.Processes[0].Args).To(Equal([]string{
+					"--restart",
+					"--shell", "none",
+					"--watch", filepath.Join(workingDir, "glob*[dir]"),
+					"--ignore", workingDir + `/glob\*\[dir\]/package.json`,
+					"--ignore", workingDir + `/glob\*\[dir\]/yarn.lock`,
+					"--ignore", workingDir + `/glob\*\[dir\]/node_modules`,
+					"--ignore", workingDir + `/glob\*\[dir\]/**/node_modules`,
+					"--ignore", workingDir + `/glob\*\[dir\]/.git`,
+					"--",
+					"bash", "-c",
+					fmt.Sprintf("cd '%s/glob*[dir]' && exec node server.js", workingDir),
+				}))
+			})
+		})
+	})
+
+	context("when the launch init is enabled", func() {
+		it.Before(func() {
+			Expect(os.Unsetenv("BP_YARN_START_INIT")).To(Succeed())
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+
+			Expect(os.Mkdir(filepath.Join(cnbDir, "bin"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(cnbDir, "bin", "launch-init"), []byte("launch-init-contents"), 0755)).To(Succeed())
+		})
+
+		it("installs the init into a launch layer and wraps the launch processes with it", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Stack:      "some-stack",
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{},
+				},
+				Layers: packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(2))
+			layer := result.Layers[1]
+			Expect(layer.Name).To(Equal("launch-init"))
+			Expect(layer.Path).To(Equal(filepath.Join(layersDir, "launch-init")))
+			Expect(layer.Launch).To(BeTrue())
+			Expect(layer.Build).To(BeFalse())
+			Expect(layer.Cache).To(BeFalse())
+
+			initPath := filepath.Join(layersDir, "launch-init", "bin", "launch-init")
+			Expect(initPath).To(BeARegularFile())
+			content, err := os.ReadFile(initPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("launch-init-contents"))
+
+			Expect(result.Launch.Processes).To(Equal([]packit.Process{
+				{
+					Type:    "web",
+					Command: initPath,
+					Args: []string{
+						"bash", "-c",
+						fmt.Sprintf("cd %s/some-project-dir && some-prestart-command && some-start-command && exec some-poststart-command", workingDir),
+					},
+					Default: true,
+					Direct:  true,
+				},
+			}))
+		})
+
+		context("and BP_LIVE_RELOAD_ENABLED=true in the build environment", func() {
+			it.Before(func() {
+				t.Setenv("BP_LIVE_RELOAD_ENABLED", "true")
+			})
+
+			it("wraps every launch process", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				initPath := filepath.Join(layersDir, "launch-init", "bin", "launch-init")
+				Expect(result.Launch.Processes).To(HaveLen(2))
+				Expect(result.Launch.Processes[0].Command).To(Equal(initPath))
+				Expect(result.Launch.Processes[0].Args[0]).To(Equal("watchexec"))
+				Expect(result.Launch.Processes[1].Command).To(Equal(initPath))
+				Expect(result.Launch.Processes[1].Args[0]).To(Equal("bash"))
+			})
+		})
+	})
+
+	context("when the package.json does not include a prestart command", func() {
+		it.Before(func() {
+			err := os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+				"scripts": {
+					"start": "some-start-command",
+					"poststart": "some-poststart-command"
+				}
+			}`), 0600)
+			Expect(err).NotTo(HaveOccurred())
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+		})
+
+		it("returns a result with a valid start command", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Stack:      "some-stack",
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{},
+				},
+				Layers: packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Launch).To(Equal(packit.LaunchMetadata{
+				Processes: []packit.Process{
+					{
+						Type:    "web",
+						Command: "bash",
+						Args: []string{
+							"-c",
+							fmt.Sprintf("cd %s/some-project-dir && some-start-command && exec some-poststart-command", workingDir),
+						},
+						Default: true,
+						Direct:  true,
+					},
+				},
+			}))
+		})
+	})
+
+	context("when the package.json does not include a poststart command", func() {
+		it.Before(func() {
+			err := os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+				"scripts": {
+					"prestart": "some-prestart-command",
+					"start": "some-start-command"
+				}
+			}`), 0600)
+			Expect(err).NotTo(HaveOccurred())
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+		})
+
+		it("returns a result with a valid start command", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Stack:      "some-stack",
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{},
+				},
+				Layers: packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Launch).To(Equal(packit.LaunchMetadata{
+				Processes: []packit.Process{
+					{
+						Type:    "web",
+						Command: "bash",
+						Args: []string{
+							"-c",
+							fmt.Sprintf("cd %s/some-project-dir && some-prestart-command && exec some-start-command", workingDir),
+						},
+						Default: true,
+						Direct:  true,
+					},
+				},
+			}))
+		})
+	})
+
+	context("when the package.json does not include a start command", func() {
+		it.Before(func() {
+			err := os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+				"scripts": {
+					"prestart": "some-prestart-command",
+					"poststart": "some-poststart-command"
+				}
+			}`), 0600)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "server.js"), nil, 0600)).To(Succeed())
+			t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+		})
+
+		it("returns a result with a valid start command", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Stack:      "some-stack",
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{},
+				},
+				Layers: packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Launch).To(Equal(packit.LaunchMetadata{
+				Processes: []packit.Process{
+					{
+						Type:    "web",
+						Command: "bash",
+						Args: []string{
+							"-c",
+							fmt.Sprintf("cd %s/some-project-dir && some-prestart-command && node server.js && exec some-poststart-command", workingDir),
+						},
+						Default: true,
+						Direct:  true,
+					},
+				},
+			}))
+
+			Expect(buffer.String()).To(ContainSubstring(`No "start" script found in package.json`))
+			Expect(buffer.String()).To(ContainSubstring("Using server.js from the default entrypoints as the entrypoint (CommonJS)"))
+		})
+
+		context("when package.json has a main field", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"type": "module",
+					"main": "./dist/app",
+					"bin": "bin/cli.js"
+				}`), 0600)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "some-project-dir", "dist"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "dist", "app.js"), nil, 0600)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "some-project-dir", "bin"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "bin", "cli.js"), nil, 0600)).To(Succeed())
+			})
+
+			it("starts the file named by the main field", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.Processes).To(Equal([]packit.Process{
+					{
+						Type:    "web",
+						Command: "bash",
+						Args: []string{
+							"-c",
+							fmt.Sprintf("cd %s/some-project-dir && exec node dist/app.js", workingDir),
+						},
+						Default: true,
+						Direct:  true,
+					},
+				}))
+
+				Expect(buffer.String()).To(ContainSubstring(`Using dist/app.js from the "main" field as the entrypoint (ES module)`))
+			})
+		})
+
+		context("when package.json has a single bin entry", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"main": "does-not-exist.js",
+					"bin": {
+						"some-server": "bin/server.mjs"
+					}
+				}`), 0600)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "some-project-dir", "bin"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "bin", "server.mjs"), nil, 0600)).To(Succeed())
+			})
+
+			it("starts the file named by the bin entry", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.Processes).To(Equal([]packit.Process{
+					{
+						Type:    "web",
+						Command: "bash",
+						Args: []string{
+							"-c",
+							fmt.Sprintf("cd %s/some-project-dir && exec node bin/server.mjs", workingDir),
+						},
+						Default: true,
+						Direct:  true,
+					},
+				}))
+
+				Expect(buffer.String()).To(ContainSubstring(`Using bin/server.mjs from the "some-server" bin entry as the entrypoint (ES module)`))
+			})
+		})
+
+		context("when package.json has several bin entries", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"bin": {
+						"some-server": "server.js",
+						"some-cli": "cli.js"
+					}
+				}`), 0600)).To(Succeed())
+				Expect(os.Remove(filepath.Join(workingDir, "some-project-dir", "server.js"))).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "cli.js"), nil, 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "index.js"), nil, 0600)).To(Succeed())
+			})
+
+			it("falls back to the default entrypoints", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.Processes[0].Args).To(Equal([]string{
+					"-c",
+					fmt.Sprintf("cd %s/some-project-dir && exec node index.js", workingDir),
+				}))
+			})
+		})
+
+		context("when there is no entrypoint", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "some-project-dir", "server.js"))).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(`no "start" script in package.json and no entrypoint found: expected the "main" field, a single "bin" entry or one of server.js, index.js, server.mjs to exist`))
+			})
+		})
+	})
+
+	context("when the project-path env var is not set", func() {
+		it.Before(func() {
+			err := os.WriteFile(filepath.Join(workingDir, "package.json"), []byte(`{
+				"scripts": {
+					"prestart": "some-prestart-command",
+					"start": "some-start-command",
+					"poststart": "some-poststart-command"
+				}
+			}`), 0600)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.Remove(filepath.Join(workingDir, "package.json"))).To(Succeed())
+		})
+
+		it("returns a result with a valid start command", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Stack:      "some-stack",
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{},
+				},
+				Layers: packit.Layers{Path: layersDir},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Launch).To(Equal(packit.LaunchMetadata{
+				Processes: []packit.Process{
+					{
+						Type:    "web",
+						Command: "bash",
+						Args: []string{
+							"-c",
+							"some-prestart-command && some-start-command && exec some-poststart-command",
+						},
+						Default: true,
+						Direct:  true,
+					},
+				},
+			}))
+		})
+
+		context("when the start script is a simple command", func() {
+			it.Before(func() {
+				err := os.WriteFile(filepath.Join(workingDir, "package.json"), []byte(`{
+					"scripts": {
+						"start": "node --title 'some server' server.js"
+					}
+				}`), 0600)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it("returns an exec-form start command", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.Processes).To(Equal([]packit.Process{
+					{
+						Type:    "web",
+						Command: "node",
+						Args:    []string{"--title", "some server", "server.js"},
+						Default: true,
+						Direct:  true,
+					},
+				}))
+			})
+
+			context("when the command is exec'd by the script itself", func() {
+				it.Before(func() {
+					err := os.WriteFile(filepath.Join(workingDir, "package.json"), []byte(`{
+						"scripts": {
+							"start": "exec node server.js"
+						}
+					}`), 0600)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				it("drops the exec from the exec-form start command", func() {
+					result, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						CNBPath:    cnbDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Launch.Processes).To(Equal([]packit.Process{
+						{
+							Type:    "web",
+							Command: "node",
+							Args:    []string{"server.js"},
+							Default: true,
+							Direct:  true,
+						},
+					}))
+				})
+			})
+		})
+
+		context("when the start script needs a shell", func() {
+			it("runs the start script through bash", func() {
+				for _, script := range []string{
+					"node server.js && echo done",
+					"node server.js | tee log",
+					"node server.js > log",
+					`node "$ENTRYPOINT"`,
+					"node `cat entrypoint`",
+					"node dist/*.js",
+					"node ~/server.js",
+					"node server.js # comment",
+					"NODE_ENV=$ENV node server.js",
+					"node 'server.js",
+					"time node server.js",
+					"echo starting && time node server.js",
+					"if true; then node server.js; fi",
+				} {
+					content, err := json.Marshal(map[string]interface{}{
+						"scripts": map[string]string{"start": script},
+					})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(os.WriteFile(filepath.Join(workingDir, "package.json"), content, 0600)).To(Succeed())
+
+					result, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						CNBPath:    cnbDir,
+						Stack:      "some-stack",
+						BuildpackInfo: packit.BuildpackInfo{
+							Name:    "Some Buildpack",
+							Version: "some-version",
+						},
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{},
+						},
+						Layers: packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Launch.Processes).To(Equal([]packit.Process{
+						{
+							Type:    "web",
+							Command: "bash",
+							Args:    []string{"-c", script},
+							Default: true,
+							Direct:  true,
+						},
+					}), script)
+				}
+			})
+		})
+
+		context("when the start script assigns variables in front of the command", func() {
+			var buildWeb = func(script string) (packit.Process, packit.Environment) {
+				content, err := json.Marshal(map[string]interface{}{
+					"scripts": map[string]string{"start": script},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.WriteFile(filepath.Join(workingDir, "package.json"), content, 0600)).To(Succeed())
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				return result.Launch.Processes[0], result.Layers[0].ProcessLaunchEnv["web"]
+			}
+
+			it("moves the variables into the environment of the process", func() {
+				for _, example := range []struct {
+					script  string
+					command []string
+					env     map[string]string
+				}{
+					{
+						script:  "NODE_ENV=production PORT=3000 node server.js",
+						command: []string{"node", "server.js"},
+						env:     map[string]string{"NODE_ENV": "production", "PORT": "3000"},
+					},
+					{
+						script:  `cross-env NODE_ENV=production "NODE_OPTIONS=--max-old-space-size=4096 --enable-source-maps" node server.js`,
+						command: []string{"node", "server.js"},
+						env:     map[string]string{"NODE_ENV": "production", "NODE_OPTIONS": "--max-old-space-size=4096 --enable-source-maps"},
+					},
+					{
+						script:  `GREETING='hello world' node server.js --title "some server"`,
+						command: []string{"node", "server.js", "--title", "some server"},
+						env:     map[string]string{"GREETING": "hello world"},
+					},
+					{
+						script:  `CACHE_DIR='~/cache' node server.js`,
+						command: []string{"node", "server.js"},
+						env:     map[string]string{"CACHE_DIR": "~/cache"},
+					},
+				} {
+					process, env := buildWeb(example.script)
+					Expect(append([]string{process.Command}, process.Args...)).To(Equal(example.command), example.script)
+					for name, value := range example.env {
+						Expect(env).To(HaveKeyWithValue(name+".override", value), example.script)
+					}
+					Expect(env).To(HaveKeyWithValue("npm_lifecycle_script.override", example.script))
+				}
+			})
+
+			it("leaves commands alone when the variables cannot be moved", func() {
+				for _, script := range []string{
+					"PATH=/opt/bin node server.js",
+					"NODE_ENV=production",
+					`"NODE_ENV=production" node server.js`,
+					"NODE_ENV=production node server.js && echo done",
+					"CACHE_DIR=~/cache node server.js",
+					"NODE_PATH=lib:~/lib node server.js",
+					"cross-env CACHE_DIR=~/cache node server.js",
+				} {
+					process, _ := buildWeb(script)
+					Expect(process.Command).To(Equal("bash"), script)
+					Expect(process.Args).To(Equal([]string{"-c", script}), script)
+				}
+			})
+
+			context("when BP_YARN_START_LIFT_ENV is false", func() {
+				it.Before(func() {
+					t.Setenv("BP_YARN_START_LIFT_ENV", "false")
+				})
+
+				it("runs the start script through bash", func() {
+					process, env := buildWeb("NODE_ENV=production node server.js")
+					Expect(process.Command).To(Equal("bash"))
+					Expect(process.Args).To(Equal([]string{"-c", "NODE_ENV=production node server.js"}))
+					Expect(env).NotTo(HaveKey("NODE_ENV.override"))
+				})
+			})
+		})
+
+		context("when the last script is a shell builtin", func() {
+			it.Before(func() {
+				err := os.WriteFile(filepath.Join(workingDir, "package.json"), []byte(`{
+					"scripts": {
+						"prestart": "some-prestart-command",
+						"start": "exec some-start-command",
+						"poststart": "exit 0"
+					}
+				}`), 0600)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it("leaves the builtins to bash without exec'ing them", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.Processes[0].Command).To(Equal("bash"))
+				Expect(result.Launch.Processes[0].Args).To(Equal([]string{
+					"-c",
+					"some-prestart-command && exec some-start-command && exit 0",
+				}))
+
+				for _, script := range []string{"exit 0", ":", "cd dist", "source .env", "export NODE_ENV=production", "exec"} {
+					content, err := json.Marshal(map[string]interface{}{
+						"scripts": map[string]string{"prestart": "some-prestart-command", "start": script},
+					})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(os.WriteFile(filepath.Join(workingDir, "package.json"), content, 0600)).To(Succeed())
+
+					result, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						CNBPath:    cnbDir,
+						Layers:     packit.Layers{Path: layersDir},
+					})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Launch.Processes[0].Args).To(Equal([]string{"-c", "some-prestart-command && " + script}), script)
+				}
+			})
+		})
+
+		context("when the last script needs a shell", func() {
+			it.Before(func() {
+				err := os.WriteFile(filepath.Join(workingDir, "package.json"), []byte(`{
+					"scripts": {
+						"prestart": "some-prestart-command",
+						"start": "some-start-command | tee some.log"
+					}
+				}`), 0600)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it("does not exec the last script", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Launch.Processes).To(Equal([]packit.Process{
+					{
+						Type:    "web",
+						Command: "bash",
+						Args: []string{
+							"-c",
+							"some-prestart-command && some-start-command | tee some.log",
+						},
+						Default: true,
+						Direct:  true,
+					},
+				}))
+			})
+		})
+	})
+
+	context("failure cases", func() {
+		context("when package.json does not exist", func() {
+			it.Before(func() {
+				Expect(os.RemoveAll(filepath.Join(workingDir, "some-project-dir", "package.json"))).To(Succeed())
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			})
+
+			it("fails with the appropriate error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("open %s: no such file or directory", filepath.Join(workingDir, "some-project-dir", "package.json")))))
+			})
+		})
+
+		context("when package.json is malformed", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte("%%%"), os.ModePerm)).To(Succeed())
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			})
+
+			it("fails with the appropriate error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("unable to decode package.json invalid character '%' looking for beginning of value")))
+			})
+		})
+
+		context("when the workspaces field of package.json is malformed", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"workspaces": "packages/*"
+				}`), 0600)).To(Succeed())
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+				t.Setenv("BP_YARN_START_WORKSPACE", "@acme/api")
+			})
+
+			it("fails with the appropriate error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("unable to decode package.json workspaces field")))
+			})
+		})
+
+		context("when a workspace package.json is malformed", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"workspaces": ["packages/*"]
+				}`), 0600)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "some-project-dir", "packages", "api"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "packages", "api", "package.json"), []byte("%%%"), 0600)).To(Succeed())
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+				t.Setenv("BP_YARN_START_WORKSPACE", "@acme/api")
+			})
+
+			it("fails with the appropriate error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to find workspaces: failed to parse workspace packages/api: unable to decode package.json")))
+			})
+		})
+
+		context("when the config field of package.json is malformed", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", "package.json"), []byte(`{
+					"config": {"port": 80, }
+				}`), 0600)).To(Succeed())
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			})
+
+			it("fails with the appropriate error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("unable to decode package.json")))
+			})
+		})
+
+		context("when BP_YARN_START_INLINE_SCRIPTS is set to an invalid value", func() {
+			it.Before(func() {
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+				t.Setenv("BP_YARN_START_INLINE_SCRIPTS", "not-a-bool")
+			})
+
+			it("fails with the appropriate error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to parse BP_YARN_START_INLINE_SCRIPTS value not-a-bool")))
+			})
+		})
+
+		context("when BP_DEBUG_ENABLED is set to an invalid value", func() {
+			it.Before(func() {
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+				t.Setenv("BP_DEBUG_ENABLED", "not-a-bool")
+			})
+
+			it("fails with the appropriate error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to parse BP_DEBUG_ENABLED value not-a-bool")))
+			})
+		})
+
+		context("when BP_YARN_START_LIFT_ENV is set to an invalid value", func() {
+			it.Before(func() {
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+				t.Setenv("BP_YARN_START_LIFT_ENV", "not-a-bool")
+			})
+
+			it("fails with the appropriate error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to parse BP_YARN_START_LIFT_ENV value not-a-bool")))
+			})
+		})
+
+		context("when BP_YARN_START_PROCESSES is malformed", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_PROCESSES", "worker")
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			})
+
+			it("returns an error", func() {
 				_, err := build(packit.BuildContext{
 					WorkingDir: workingDir,
 					CNBPath:    cnbDir,
@@ -389,7 +3099,199 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					},
 					Layers: packit.Layers{Path: layersDir},
 				})
-				Expect(err).To(MatchError(ContainSubstring("unable to decode package.json invalid character '%' looking for beginning of value")))
+				Expect(err).To(MatchError("failed to parse BP_YARN_START_PROCESSES value worker: expected entries of the form <type>=<script>"))
+			})
+		})
+
+		context("when BP_YARN_START_PROCESSES contains an invalid process type", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_PROCESSES", "some worker=start")
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to parse BP_YARN_START_PROCESSES value some worker=start: invalid process type \"some worker\""))
+			})
+		})
+
+		context("when BP_YARN_START_PROCESSES reuses a process type", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_PROCESSES", "web=start")
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to parse BP_YARN_START_PROCESSES value web=start: process type \"web\" is already in use"))
+			})
+		})
+
+		context("when BP_YARN_START_PROCESSES names a script that does not exist", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_PROCESSES", "worker=queue:work")
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to add the worker process: no \"queue:work\" script in package.json"))
+			})
+		})
+
+		context("when the project uses Yarn Plug'n'Play but there is no .pnp.cjs", func() {
+			it.Before(func() {
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project-dir", ".yarnrc.yml"), []byte("nodeLinker: pnp\n"), 0600)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(fmt.Sprintf("the project uses Yarn Plug'n'Play but .pnp.cjs does not exist in %s/some-project-dir", workingDir)))
+			})
+		})
+
+		context("when the buildpack.toml cannot be parsed", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte("%%%"), 0600)).To(Succeed())
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to parse buildpack.toml")))
+			})
+		})
+
+		context("when the buildpack API version is invalid", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`api = "latest"`), 0600)).To(Succeed())
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring(`failed to parse buildpack API version "latest"`)))
+			})
+		})
+
+		context("when the launch init cannot be installed", func() {
+			it.Before(func() {
+				Expect(os.Unsetenv("BP_YARN_START_INIT")).To(Succeed())
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to install launch init")))
+			})
+		})
+
+		context("when BP_YARN_START_INIT is set to an invalid value", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_INIT", "not-a-bool")
+				t.Setenv("BP_NODE_PROJECT_PATH", "some-project-dir")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Stack:      "some-stack",
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{},
+					},
+					Layers: packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to parse BP_YARN_START_INIT value not-a-bool")))
 			})
 		})
 
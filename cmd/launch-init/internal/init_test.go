@@ -0,0 +1,14 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitLaunchInit(t *testing.T) {
+	suite := spec.New("launch-init", spec.Report(report.Terminal{}), spec.Sequential())
+	suite("Supervise", testSupervise)
+	suite.Run(t)
+}
@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// prSetChildSubreaper is the prctl option that makes the calling process the
+// subreaper of its descendants.
+const prSetChildSubreaper = 36
+
+// becomeSubreaper makes orphaned descendants get re-parented to this process
+// rather than to PID 1, so that they are reaped even when this process does
+// not run as PID 1 itself.
+func becomeSubreaper() error {
+	_, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// isTerminal reports whether fd refers to a terminal.
+func isTerminal(fd uintptr) bool {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(&termios)))
+	return errno == 0
+}
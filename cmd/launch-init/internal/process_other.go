@@ -0,0 +1,15 @@
+//go:build !linux
+
+package internal
+
+// becomeSubreaper does nothing outside of Linux, where orphans are always
+// re-parented to PID 1.
+func becomeSubreaper() error {
+	return nil
+}
+
+// isTerminal reports that fd is not a terminal outside of Linux, which the
+// launch init does not run on.
+func isTerminal(fd uintptr) bool {
+	return false
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// drainTimeout bounds how long the rest of the process group, and any orphan,
+// may keep running once the command itself has exited.
+const drainTimeout = 10 * time.Second
+
+// reapInterval is how often children are reaped even without a SIGCHLD, which
+// the signal channel may have dropped.
+const reapInterval = 250 * time.Millisecond
+
+// Supervise runs the given command in its own process group and waits for it
+// to exit. Every signal received in the meantime is forwarded to that process
+// group, and any other child process that exits, such as an orphan that was
+// re-parented to this process, is reaped. Once the command has exited, the
+// processes it leaves behind, such as a node server that bash started and
+// that is still running its shutdown handler, get up to drainTimeout to exit
+// as well, since they are killed as soon as this process exits as PID 1. The
+// returned code is the exit status of the command, or 128 plus the signal
+// number when it was terminated by a signal.
+func Supervise(command string, args []string) (int, error) {
+	err := becomeSubreaper()
+	if err != nil {
+		return 0, fmt.Errorf("failed to become the subreaper of %s: %w", command, err)
+	}
+
+	signals := make(chan os.Signal, 32)
+	signal.Notify(signals)
+	defer signal.Stop(signals)
+
+	cmd := exec.Command(command, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	// With a terminal attached, as with docker run -t, the process group of
+	// the command has to be the foreground one, or reading from the terminal
+	// would stop it with SIGTTIN.
+	if isTerminal(os.Stdin.Fd()) {
+		cmd.SysProcAttr.Foreground = true
+		cmd.SysProcAttr.Ctty = int(os.Stdin.Fd())
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", command, err)
+	}
+
+	pid := cmd.Process.Pid
+
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+
+	var (
+		code     int
+		exited   bool
+		deadline <-chan time.Time
+	)
+
+	for {
+		for {
+			var status syscall.WaitStatus
+			reaped, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+			if errors.Is(err, syscall.EINTR) {
+				continue
+			}
+
+			if errors.Is(err, syscall.ECHILD) && exited {
+				return code, nil
+			}
+
+			if err != nil || reaped <= 0 {
+				break
+			}
+
+			if reaped == pid {
+				code, exited = exitCode(status), true
+				deadline = time.After(drainTimeout)
+			}
+		}
+
+		select {
+		case sig := <-signals:
+			switch sig {
+			// SIGURG is used internally by the Go runtime to preempt goroutines.
+			case syscall.SIGCHLD, syscall.SIGURG:
+
+			default:
+				_ = syscall.Kill(-pid, sig.(syscall.Signal))
+			}
+
+		case <-ticker.C:
+
+		case <-deadline:
+			return code, nil
+		}
+	}
+}
+
+func exitCode(status syscall.WaitStatus) int {
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+
+	return status.ExitStatus()
+}
@@ -0,0 +1,151 @@
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/paketo-buildpacks/yarn-start/cmd/launch-init/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSupervise(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect     = NewWithT(t).Expect
+		Eventually = NewWithT(t).Eventually
+
+		tmpDir string
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "launch-init")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	it("returns the exit status of the command", func() {
+		code, err := internal.Supervise("sh", []string{"-c", "exit 3"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(Equal(3))
+	})
+
+	context("when the command is terminated by a signal", func() {
+		it("returns 128 plus the signal number", func() {
+			code, err := internal.Supervise("sh", []string{"-c", "kill -TERM $$"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(128 + int(syscall.SIGTERM)))
+		})
+	})
+
+	context("when a signal is received", func() {
+		it("forwards the signal to the command", func() {
+			ready := filepath.Join(tmpDir, "ready")
+			output := filepath.Join(tmpDir, "output")
+
+			type result struct {
+				code int
+				err  error
+			}
+			done := make(chan result, 1)
+
+			go func() {
+				code, err := internal.Supervise("sh", []string{"-c",
+					`trap 'echo forwarded > "$1"; exit 7' TERM; touch "$0"; while true; do sleep 0.1; done`,
+					ready, output,
+				})
+				done <- result{code, err}
+			}()
+
+			Eventually(ready).Should(BeAnExistingFile())
+			Expect(syscall.Kill(os.Getpid(), syscall.SIGTERM)).To(Succeed())
+
+			var r result
+			Eventually(done).Should(Receive(&r))
+			Expect(r.err).NotTo(HaveOccurred())
+			Expect(r.code).To(Equal(7))
+
+			content, err := os.ReadFile(output)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("forwarded\n"))
+		})
+	})
+
+	context("when the command exits before the rest of its process group", func() {
+		it("waits for the other processes to finish shutting down", func() {
+			ready := filepath.Join(tmpDir, "ready")
+			output := filepath.Join(tmpDir, "output")
+
+			type result struct {
+				code int
+				err  error
+			}
+			done := make(chan result, 1)
+
+			// The outer shell dies on SIGTERM right away, like bash running a
+			// chain, while the inner one takes a while to shut down.
+			go func() {
+				code, err := internal.Supervise("sh", []string{"-c",
+					`sh -c "$2" "$0" "$1" & wait`,
+					ready, output,
+					`trap 'sleep 1; echo finished > "$1"; exit 0' TERM; touch "$0"; while true; do sleep 0.1; done`,
+				})
+				done <- result{code, err}
+			}()
+
+			Eventually(ready).Should(BeAnExistingFile())
+			Expect(syscall.Kill(os.Getpid(), syscall.SIGTERM)).To(Succeed())
+
+			var r result
+			Eventually(done, "5s").Should(Receive(&r))
+			Expect(r.err).NotTo(HaveOccurred())
+			Expect(r.code).To(Equal(128 + int(syscall.SIGTERM)))
+
+			content, err := os.ReadFile(output)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("finished\n"))
+		})
+	})
+
+	context("when the command leaves an orphaned process behind", func() {
+		it("reaps the orphan once it exits", func() {
+			pidFile := filepath.Join(tmpDir, "orphan")
+
+			// The subshell exits right away, which orphans the sleep it started,
+			// while the command itself outlives the sleep.
+			code, err := internal.Supervise("sh", []string{"-c",
+				`( sleep 0.2 & echo $! > "$0" ); sleep 1`,
+				pidFile,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(code).To(Equal(0))
+
+			content, err := os.ReadFile(pidFile)
+			Expect(err).NotTo(HaveOccurred())
+
+			pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+			Expect(err).NotTo(HaveOccurred())
+
+			// An orphan that was not reaped would linger as a zombie, which can
+			// still be signalled.
+			Expect(syscall.Kill(pid, 0)).To(MatchError(syscall.ESRCH))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the command cannot be started", func() {
+			it("returns an error", func() {
+				_, err := internal.Supervise("does-not-exist", nil)
+				Expect(err).To(MatchError(ContainSubstring("failed to start does-not-exist")))
+			})
+		})
+	})
+}
@@ -0,0 +1,331 @@
+package yarnstart
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// shellMetacharacters are the characters that, when they appear outside of
+// quotes, mean a script has to be interpreted by a shell.
+const shellMetacharacters = "|&;<>()$`\\*?[]{}!\n"
+
+// shellSafeCharacters are the characters that never need to be quoted when
+// they make up a word in a bash command line.
+const shellSafeCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%+=:,./_-"
+
+// globMetacharacters are the characters that have a special meaning in the
+// glob patterns watchexec accepts.
+const globMetacharacters = "\\*?[]{}!"
+
+// yarnInvocationPattern matches yarn, or its yarnpkg alias, as a word of a
+// script.
+var yarnInvocationPattern = regexp.MustCompile("(^|[\\s;&|(`\"'])(yarn|yarnpkg)($|[\\s;&|)`\"'])")
+
+// environmentNamePattern matches the names that bash accepts in a variable
+// assignment.
+var environmentNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// shellBuiltins are the shell builtins, other than the stateBuiltins, that
+// exist only inside a shell and therefore cannot be executed on their own.
+var shellBuiltins = []string{
+	":", "bg", "break", "builtin", "command", "continue", "fg", "hash", "jobs",
+	"let", "read", "return", "shift", "type", "wait",
+}
+
+// reservedWords are the words that bash reads as part of its grammar, such as
+// the start of a compound command, rather than as the name of a command.
+var reservedWords = []string{
+	"!", "[[", "]]", "{", "}", "case", "coproc", "do", "done", "elif", "else",
+	"esac", "fi", "for", "function", "if", "in", "select", "then", "time",
+	"until", "while",
+}
+
+// A word is a word of a simple command, with its quotes removed.
+type word struct {
+	Text string
+
+	// Quoted is the offset in Text at which the first quoted part of the word
+	// starts, or -1 when no part of it is quoted.
+	Quoted int
+}
+
+// splitCommand tokenizes a script into its words when it is a simple
+// command: one that contains no operators, expansions, redirections or
+// variable assignments and can therefore be executed without a shell.
+func splitCommand(script string) ([]string, bool) {
+	words, ok := splitWords(script)
+	if !ok || len(words) == 0 || strings.Contains(words[0].Text, "=") {
+		return nil, false
+	}
+
+	fields := make([]string, len(words))
+	for i, w := range words {
+		fields[i] = w.Text
+	}
+
+	return fields, true
+}
+
+// splitWords tokenizes a script into its words when it contains no operators,
+// expansions or redirections.
+func splitWords(script string) ([]word, bool) {
+	var (
+		words   []word
+		field   strings.Builder
+		inField bool
+		quoted  = -1
+		quote   rune
+	)
+
+	for _, r := range script {
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+				continue
+			}
+			field.WriteRune(r)
+
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '$', '`', '\\':
+				return nil, false
+			default:
+				field.WriteRune(r)
+			}
+
+		case r == '\'' || r == '"':
+			quote = r
+			inField = true
+			if quoted < 0 {
+				quoted = field.Len()
+			}
+
+		case r == ' ' || r == '\t':
+			if inField {
+				words = append(words, word{Text: field.String(), Quoted: quoted})
+				field.Reset()
+				inField = false
+				quoted = -1
+			}
+
+		case strings.ContainsRune(shellMetacharacters, r):
+			return nil, false
+
+		case (r == '#' || r == '~') && !inField:
+			return nil, false
+
+		// Bash also expands a ~ after the = of an assignment, and after a colon
+		// in its value, as in CACHE_DIR=~/cache or NODE_PATH=lib:~/lib.
+		case r == '~' && (strings.HasSuffix(field.String(), "=") || strings.HasSuffix(field.String(), ":")):
+			return nil, false
+
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, false
+	}
+
+	if inField {
+		words = append(words, word{Text: field.String(), Quoted: quoted})
+	}
+
+	return words, true
+}
+
+// execFields tokenizes a script into its words when it is a simple command
+// that runs an executable, which can then replace the shell. An exec in front
+// of the command is dropped, and shell builtins are left to a shell.
+func execFields(script string) ([]string, bool) {
+	fields, ok := splitCommand(script)
+	if ok && fields[0] == "exec" {
+		fields = fields[1:]
+	}
+
+	if !ok || len(fields) == 0 || !isExecutable(fields[0]) {
+		return nil, false
+	}
+
+	return fields, true
+}
+
+// isExecutable reports whether name is run as an executable rather than by
+// the shell itself, and is not an option of the exec builtin.
+func isExecutable(name string) bool {
+	return !strings.HasPrefix(name, "-") && !strings.Contains(name, "=") && !slices.Contains(stateBuiltins, name) && !slices.Contains(shellBuiltins, name) && !slices.Contains(reservedWords, name)
+}
+
+// assignment returns the variable that the word assigns to and its value,
+// when the word is a variable assignment to bash.
+func (w word) assignment() (string, string, bool) {
+	name, value, ok := strings.Cut(w.Text, "=")
+	if !ok || (w.Quoted >= 0 && w.Quoted <= len(name)) || !environmentNamePattern.MatchString(name) {
+		return "", "", false
+	}
+
+	return name, value, true
+}
+
+// liftEnvironment moves the variable assignments at the front of a single
+// simple command, as in NODE_ENV=production node server.js or
+// cross-env NODE_ENV=production node server.js, out of the command. It
+// returns the assigned variables along with the command that remains, which
+// no longer needs a shell. Any other segments are returned unchanged.
+func liftEnvironment(segments []string) (map[string]string, []string) {
+	if len(segments) != 1 {
+		return nil, segments
+	}
+
+	words, ok := splitWords(segments[0])
+	if !ok {
+		return nil, segments
+	}
+
+	env := map[string]string{}
+	crossEnv := false
+	for len(words) > 0 {
+		if words[0].Text == "cross-env" && words[0].Quoted < 0 && !crossEnv {
+			crossEnv = true
+			words = words[1:]
+			continue
+		}
+
+		name, value, ok := words[0].assignment()
+		if !ok && crossEnv {
+			// cross-env parses its arguments itself, after the shell has removed
+			// the quotes.
+			name, value, ok = strings.Cut(words[0].Text, "=")
+			ok = ok && environmentNamePattern.MatchString(name)
+		}
+
+		if !ok {
+			break
+		}
+
+		// The PATH of the process is built from the executables of the
+		// package, which an assignment would replace.
+		if name == "PATH" {
+			return nil, segments
+		}
+
+		env[name] = value
+		words = words[1:]
+	}
+
+	if len(env) == 0 && !crossEnv {
+		return nil, segments
+	}
+
+	if len(words) == 0 || strings.Contains(words[0].Text, "=") {
+		return nil, segments
+	}
+
+	fields := make([]string, len(words))
+	for i, w := range words {
+		fields[i] = w.Text
+	}
+
+	return env, []string{shellJoin(fields...)}
+}
+
+// composeCommand returns the command and arguments of a launch process that
+// runs the given script segments in order, after changing into dir when it is
+// not empty. A single simple command that needs no change of directory is
+// executed directly so that it receives the signals sent to the container.
+// Anything else is chained together in bash, with the final segment exec'd
+// when it is a simple command that runs an executable, so that it replaces the
+// shell.
+func composeCommand(dir string, segments []string) (string, []string) {
+	if len(segments) == 1 && dir == "" {
+		if fields, ok := execFields(segments[0]); ok {
+			return fields[0], fields[1:]
+		}
+	}
+
+	chain := make([]string, len(segments))
+	copy(chain, segments)
+
+	last := len(chain) - 1
+	if fields, ok := splitCommand(chain[last]); ok && isExecutable(fields[0]) {
+		chain[last] = "exec " + chain[last]
+	}
+
+	if dir != "" {
+		chain = append([]string{shellJoin("cd", dir)}, chain...)
+	}
+
+	return "bash", []string{"-c", strings.Join(chain, " && ")}
+}
+
+// shellQuote returns s quoted so that bash reads it back as a single word
+// with its literal value.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+
+	safe := true
+	for _, r := range s {
+		if !strings.ContainsRune(shellSafeCharacters, r) {
+			safe = false
+			break
+		}
+	}
+
+	if safe {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellJoin quotes each of the given words and joins them into a command
+// line for bash.
+func shellJoin(words ...string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = shellQuote(word)
+	}
+
+	// A leading word such as NAME=value would be read as a variable assignment
+	// rather than as the command to run.
+	if len(words) > 0 && strings.Contains(words[0], "=") && quoted[0] == words[0] {
+		quoted[0] = "'" + words[0] + "'"
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// globEscape escapes the glob metacharacters in path, so that watchexec
+// treats an ignore pattern built from it as that literal path.
+func globEscape(path string) string {
+	var escaped strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(globMetacharacters, r) {
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(r)
+	}
+
+	return escaped.String()
+}
+
+// invokesYarn reports whether any of the given scripts runs yarn. It errs on
+// the side of finding yarn, since a missing yarn breaks the process at launch
+// whereas an unused one only makes the image larger.
+func invokesYarn(scripts []string) bool {
+	for _, script := range scripts {
+		if yarnInvocationPattern.MatchString(script) {
+			return true
+		}
+	}
+
+	return false
+}
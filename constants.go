@@ -5,3 +5,19 @@ const (
 	NodeModules = "node_modules"
 	Yarn        = "yarn"
 )
+
+// LaunchInit is the name of the init executable that supervises launch
+// processes, and of the layer it is installed into.
+const LaunchInit = "launch-init"
+
+// LaunchEnv is the name of the layer that holds the environment variables
+// that the start command needs at launch.
+const LaunchEnv = "launch-env"
+
+// LiveReloadInstall is the name of the layer that holds the script which
+// reinstalls the dependencies of a live reloaded project.
+const LiveReloadInstall = "live-reload-install"
+
+// DebugPreload is the name of the layer that holds the script which opens the
+// Node.js inspector of the debug processes.
+const DebugPreload = "debug-preload"
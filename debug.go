@@ -0,0 +1,60 @@
+package yarnstart
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultDebugPort is the port that the Node.js inspector listens on by
+// default.
+const defaultDebugPort = "9229"
+
+func checkDebugEnabled() (bool, error) {
+	return parseBoolEnv("BP_DEBUG_ENABLED", false)
+}
+
+// debugPort returns the port that BP_NODE_DEBUG_PORT chooses for the Node.js
+// inspector of the debug processes.
+func debugPort() (string, error) {
+	value := os.Getenv("BP_NODE_DEBUG_PORT")
+	if value == "" {
+		return defaultDebugPort, nil
+	}
+
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return "", fmt.Errorf("failed to parse BP_NODE_DEBUG_PORT value %s: expected a port number between 1 and 65535", value)
+	}
+
+	return strconv.Itoa(port), nil
+}
+
+// debugPreload returns the script that the debug processes preload into
+// every node process through NODE_OPTIONS. It opens the Node.js inspector on
+// every interface, so that a debugger can attach from outside the container,
+// on the port that BP_NODE_DEBUG_PORT sets at launch, or on port otherwise.
+// Yarn is a node process too, and would take the port from the app it runs,
+// so the inspector is left closed in Yarn itself. The inspector is not
+// enabled through an --inspect option for the same reason.
+func debugPreload(port string) string {
+	return strings.Join([]string{
+		"'use strict';",
+		"",
+		"const path = require('path');",
+		"const { isMainThread } = require('worker_threads');",
+		"",
+		"const yarn = /^yarn(pkg)?(-[^/]*)?(\\.c?js)?$/;",
+		"const port = process.env.BP_NODE_DEBUG_PORT || " + strconv.Quote(port) + ";",
+		"",
+		"if (isMainThread && !yarn.test(path.basename(process.argv[1] || ''))) {",
+		"  if (!/^\\d+$/.test(port) || Number(port) > 65535) {",
+		"    console.error(`Not starting the inspector: BP_NODE_DEBUG_PORT value ${port} is not a port number`);",
+		"  } else {",
+		"    require('inspector').open(Number(port), '0.0.0.0');",
+		"  }",
+		"}",
+		"",
+	}, "\n")
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 
 	"github.com/paketo-buildpacks/libnodejs"
@@ -21,16 +22,46 @@ func Detect() packit.DetectFunc {
 			return packit.DetectResult{}, err
 		}
 
+		packageManager, err := packageManagerOverride()
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		if packageManager != "" && packageManager != Yarn {
+			return packit.DetectResult{}, packit.Fail.WithMessage("BP_NODE_PACKAGE_MANAGER is set to %s", packageManager)
+		}
+
 		exists, err := fs.Exists(filepath.Join(projectPath, "yarn.lock"))
 		if err != nil {
 			return packit.DetectResult{}, fmt.Errorf("failed to stat yarn.lock: %w", err)
 		}
 
+		// Yarn keeps a single yarn.lock at the root of a workspaces project, so a
+		// workspace member relies on the lockfile of the root it belongs to.
+		rootPath := projectPath
 		if !exists {
-			return packit.DetectResult{}, packit.Fail.WithMessage("no 'yarn.lock' found in the project path %s", projectPath)
+			root, found, err := findWorkspaceRoot(context.WorkingDir, projectPath)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			if !found {
+				lockfile, owner, err := findForeignLockfile(projectPath)
+				if err != nil {
+					return packit.DetectResult{}, err
+				}
+
+				if lockfile != "" {
+					return packit.DetectResult{}, packit.Fail.WithMessage("no 'yarn.lock' found in the project path %s: found '%s' instead, which belongs to %s", projectPath, lockfile, owner)
+				}
+
+				return packit.DetectResult{}, packit.Fail.WithMessage("no 'yarn.lock' found in the project path %s", projectPath)
+			}
+
+			rootPath = root
 		}
 
-		pkg, err := libnodejs.ParsePackageJSON(projectPath)
+		pkg, err := parsePackageJSON(projectPath)
 		if err != nil {
 			if os.IsNotExist(err) {
 				return packit.DetectResult{}, packit.Fail.WithMessage("no 'package.json' found in project path %s", projectPath)
@@ -38,37 +69,235 @@ func Detect() packit.DetectFunc {
 			return packit.DetectResult{}, fmt.Errorf("failed to open package.json: %w", err)
 		}
 
-		if !pkg.HasStartScript() {
+		// The packageManager and engines fields usually live in the package.json
+		// at the workspace root.
+		rootPkg := pkg
+		if rootPath != projectPath {
+			rootPkg, err = parsePackageJSON(rootPath)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+		}
+
+		declaredPackageManager := pkg.PackageManager
+		if declaredPackageManager == "" {
+			declaredPackageManager = rootPkg.PackageManager
+		}
+
+		// A project that declares another package manager in its packageManager
+		// field is not a Yarn project, even if it also carries a yarn.lock.
+		packageManagerName, packageManagerVersion := parsePackageManager(declaredPackageManager)
+		if packageManager == "" && packageManagerName != "" && packageManagerName != Yarn {
+			return packit.DetectResult{}, packit.Fail.WithMessage("package.json declares %q as its package manager: set BP_NODE_PACKAGE_MANAGER=yarn to use Yarn anyway", declaredPackageManager)
+		}
+
+		// Without a packageManager field, the lockfile of another package manager
+		// next to yarn.lock leaves it open which one the project uses.
+		if packageManager == "" && packageManagerName == "" {
+			lockfile, _, err := findForeignLockfile(rootPath)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			if lockfile != "" {
+				return packit.DetectResult{}, packit.Fail.WithMessage("found both 'yarn.lock' and '%s' in %s: set BP_NODE_PACKAGE_MANAGER to choose the package manager", lockfile, rootPath)
+			}
+		}
+
+		startPath, startPkg := projectPath, pkg
+		if name := startWorkspaceName(); name != "" {
+			member, found, err := findWorkspace(projectPath, pkg, name)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			if !found {
+				return packit.DetectResult{}, packit.Fail.WithMessage("no workspace named %q found in %s", name, projectPath)
+			}
+
+			startPath, startPkg = member.Path, member.Package
+		}
+
+		mode, _, err := startMode(rootPath, declaredPackageManager)
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		inliner, err := newScriptInliner(rootPath, rootPkg)
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		// The web process is resolved the same way Build resolves it, so that
+		// Detect requires what the process that Build adds actually needs.
+		scriptName := startScriptName()
+		web, err := resolveWebCommand(inliner, mode, startPath, startPkg, scriptName)
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		found := web.Segments != nil
+
+		shouldStartWorkspaces, err := checkWorkspaceProcessesEnabled()
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		var members []workspace
+		if shouldStartWorkspaces {
+			members, err = startableWorkspaces(projectPath, pkg, scriptName)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+		}
+
+		if !found && len(members) == 0 {
+			if scriptName != "start" {
+				return packit.DetectResult{}, packit.Fail.WithMessage("no %q script in package.json", scriptName)
+			}
 			return packit.DetectResult{}, packit.Fail.WithMessage(NoStartScriptError)
 		}
 
+		// Yarn is only needed in the image when the launch processes run
+		// through it, or when one of the scripts they run invokes it.
+		launchYarn, ok, err := checkRequireYarn()
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		if !ok && mode == startModeYarn {
+			launchYarn, ok = true, true
+		}
+
+		if !ok {
+			scripts := slices.Clone(web.Segments)
+
+			processScripts, err := parseProcessScripts()
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			for _, processScript := range processScripts {
+				segments, err := inliner.scriptSegments(startPath, startPkg, processScript.Script, "")
+				if err != nil {
+					return packit.DetectResult{}, err
+				}
+				scripts = append(scripts, segments...)
+			}
+
+			for _, member := range members {
+				segments, err := inliner.scriptSegments(member.Path, member.Package, scriptName, "")
+				if err != nil {
+					return packit.DetectResult{}, err
+				}
+				scripts = append(scripts, segments...)
+			}
+
+			launchYarn = invokesYarn(scripts)
+		}
+
+		shouldReload, err := checkLiveReloadEnabled()
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		// Live reload only applies to the web process, which a project whose
+		// services all live in workspaces does not have.
+		shouldReload = shouldReload && found
+
+		// Reinstalling the dependencies on live reload runs yarn install at
+		// launch, over a node_modules that also holds the development
+		// dependencies, as it would after an install during the build.
+		var shouldInstall bool
+		if shouldReload {
+			shouldInstall, err = checkLiveReloadInstallEnabled()
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+		}
+
+		if shouldInstall {
+			launchYarn = true
+		}
+
+		// The dependencies of a workspace member are installed at the workspace
+		// root, so the buildpacks that provide them are told where it is.
+		launchMetadata := func() map[string]interface{} {
+			metadata := map[string]interface{}{
+				"launch": true,
+			}
+
+			if rootPath != projectPath {
+				metadata["workspace-root"] = rootPath
+			}
+
+			return metadata
+		}
+
+		nodeMetadata := launchMetadata()
+		if version := firstEngine(Node, pkg, rootPkg); version != "" {
+			nodeMetadata["version"] = version
+			nodeMetadata["version-source"] = "package.json"
+		}
+
+		// The packageManager field pins an exact Yarn version, so it is more
+		// specific than the range of engines.yarn.
+		yarnMetadata := launchMetadata()
+		yarnMetadata["launch"] = launchYarn
+		if packageManagerName == Yarn && packageManagerVersion != "" {
+			yarnMetadata["version"] = packageManagerVersion
+			yarnMetadata["version-source"] = "packageManager"
+		} else if version := firstEngine(Yarn, pkg, rootPkg); version != "" {
+			yarnMetadata["version"] = version
+			yarnMetadata["version-source"] = "package.json"
+		}
+
 		requirements := []packit.BuildPlanRequirement{
 			{
-				Name: Node,
-				Metadata: map[string]interface{}{
-					"launch": true,
-				},
-			},
-			{
-				Name: Yarn,
-				Metadata: map[string]interface{}{
-					"launch": true,
-				},
+				Name:     Node,
+				Metadata: nodeMetadata,
 			},
 			{
-				Name: NodeModules,
-				Metadata: map[string]interface{}{
-					"launch": true,
-				},
+				Name:     Yarn,
+				Metadata: yarnMetadata,
 			},
 		}
 
-		shouldReload, err := checkLiveReloadEnabled()
+		pnp, err := usesPnP(rootPath)
 		if err != nil {
 			return packit.DetectResult{}, err
 		}
 
+		// With Plug'n'Play, dependencies are resolved from the Yarn cache through
+		// the .pnp.cjs runtime, so there is no node_modules to require.
+		if !pnp {
+			nodeModulesMetadata := launchMetadata()
+			if shouldInstall {
+				nodeModulesMetadata["build"] = true
+			}
+
+			requirements = append(requirements, packit.BuildPlanRequirement{
+				Name:     NodeModules,
+				Metadata: nodeModulesMetadata,
+			})
+		}
+
+		var needsWatchexec bool
 		if shouldReload {
+			_, err = watchexecOptions()
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			engine, _, _, err := liveReloadEngine(web.Segments)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			needsWatchexec = engine == liveReloadEngineWatchexec
+		}
+
+		if needsWatchexec {
 			requirements = append(requirements, packit.BuildPlanRequirement{
 				Name: "watchexec",
 				Metadata: map[string]interface{}{
@@ -86,12 +315,42 @@ func Detect() packit.DetectFunc {
 }
 
 func checkLiveReloadEnabled() (bool, error) {
-	if reload, ok := os.LookupEnv("BP_LIVE_RELOAD_ENABLED"); ok {
-		shouldEnableReload, err := strconv.ParseBool(reload)
+	return parseBoolEnv("BP_LIVE_RELOAD_ENABLED", false)
+}
+
+func checkWorkspaceProcessesEnabled() (bool, error) {
+	return parseBoolEnv("BP_YARN_START_WORKSPACES", false)
+}
+
+// checkRequireYarn returns the value of BP_YARN_START_REQUIRE_YARN and whether
+// it is set at all.
+func checkRequireYarn() (bool, bool, error) {
+	if _, ok := os.LookupEnv("BP_YARN_START_REQUIRE_YARN"); !ok {
+		return false, false, nil
+	}
+
+	required, err := parseBoolEnv("BP_YARN_START_REQUIRE_YARN", false)
+	return required, true, err
+}
+
+// checkLiftEnvEnabled reports whether variable assignments in front of a
+// command are moved into the environment of its process, which can be turned
+// off with BP_YARN_START_LIFT_ENV.
+func checkLiftEnvEnabled() (bool, error) {
+	return parseBoolEnv("BP_YARN_START_LIFT_ENV", true)
+}
+
+func checkInitEnabled() (bool, error) {
+	return parseBoolEnv("BP_YARN_START_INIT", true)
+}
+
+func parseBoolEnv(name string, fallback bool) (bool, error) {
+	if value, ok := os.LookupEnv(name); ok {
+		parsed, err := strconv.ParseBool(value)
 		if err != nil {
-			return false, fmt.Errorf("failed to parse BP_LIVE_RELOAD_ENABLED value %s: %w", reload, err)
+			return false, fmt.Errorf("failed to parse %s value %s: %w", name, value, err)
 		}
-		return shouldEnableReload, nil
+		return parsed, nil
 	}
-	return false, nil
+	return fallback, nil
 }
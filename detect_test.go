@@ -1,6 +1,7 @@
 package yarnstart_test
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -62,7 +63,7 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 					{
 						Name: "yarn",
 						Metadata: map[string]interface{}{
-							"launch": true,
+							"launch": false,
 						},
 					},
 					{
@@ -95,7 +96,7 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 					{
 						Name: "yarn",
 						Metadata: map[string]interface{}{
-							"launch": true,
+							"launch": false,
 						},
 					},
 					{
@@ -113,46 +114,934 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				},
 				))
 			})
+
+			context("and BP_LIVE_RELOAD_ENGINE=node", func() {
+				it.Before(func() {
+					t.Setenv("BP_LIVE_RELOAD_ENGINE", "node")
+				})
+
+				var requirementNames = func() []string {
+					result, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					var names []string
+					for _, requirement := range result.Plan.Requires {
+						names = append(names, requirement.Name)
+					}
+					return names
+				}
+
+				it("does not require watchexec for a plain node command", func() {
+					Expect(requirementNames()).NotTo(ContainElement("watchexec"))
+				})
+
+				it("requires watchexec when node --watch cannot reload the start command", func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+						"scripts": {
+							"start": "node migrate.js && node server.js"
+						}
+					}`), 0600)).To(Succeed())
+
+					Expect(requirementNames()).To(ContainElement("watchexec"))
+				})
+
+				it("does not require watchexec when the start script runs a plain node command of a workspace", func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+						"workspaces": ["packages/*"],
+						"scripts": {
+							"start": "yarn workspace @acme/api start"
+						}
+					}`), 0600)).To(Succeed())
+					Expect(os.MkdirAll(filepath.Join(workingDir, "custom", "packages", "api"), os.ModePerm)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(workingDir, "custom", "packages", "api", "package.json"), []byte(`{
+						"name": "@acme/api",
+						"scripts": {
+							"start": "node server.js"
+						}
+					}`), 0600)).To(Succeed())
+
+					Expect(requirementNames()).NotTo(ContainElement("watchexec"))
+				})
+			})
+
+			context("and BP_LIVE_RELOAD_INSTALL=true", func() {
+				it.Before(func() {
+					t.Setenv("BP_LIVE_RELOAD_INSTALL", "true")
+				})
+
+				it("requires yarn at launch and node_modules during the build as well", func() {
+					result, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+						{
+							Name: "node",
+							Metadata: map[string]interface{}{
+								"launch": true,
+							},
+						},
+						{
+							Name: "yarn",
+							Metadata: map[string]interface{}{
+								"launch": true,
+							},
+						},
+						{
+							Name: "node_modules",
+							Metadata: map[string]interface{}{
+								"launch": true,
+								"build":  true,
+							},
+						},
+						{
+							Name: "watchexec",
+							Metadata: map[string]interface{}{
+								"launch": true,
+							},
+						},
+					}))
+				})
+			})
+
+			context("and BP_LIVE_RELOAD_ENGINE is set to an invalid value", func() {
+				it.Before(func() {
+					t.Setenv("BP_LIVE_RELOAD_ENGINE", "nodemon")
+				})
+
+				it("returns an error", func() {
+					_, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).To(MatchError("failed to parse BP_LIVE_RELOAD_ENGINE value nodemon: expected one of watchexec, node"))
+				})
+			})
 		})
 	})
 
-	context("when there is no yarn.lock", func() {
+	context("when the project uses Yarn Plug'n'Play", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+				"scripts": {
+					"start": "node server.js"
+				}
+			}`), 0600)).To(Succeed())
+
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "yarn.lock"), nil, 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", ".yarnrc.yml"), []byte(`yarnPath: .yarn/releases/yarn-4.1.0.cjs
+nodeLinker: "pnp" # zero-installs
+`), 0600)).To(Succeed())
+		})
+
+		it("does not require node_modules", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+				{
+					Name: "node",
+					Metadata: map[string]interface{}{
+						"launch": true,
+					},
+				},
+				{
+					Name: "yarn",
+					Metadata: map[string]interface{}{
+						"launch": false,
+					},
+				},
+			}))
+		})
+
+		context("when .yarnrc.yml does not set the node linker", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", ".yarnrc.yml"), []byte("enableTelemetry: false\n"), 0600)).To(Succeed())
+			})
+
+			it("defaults to Plug'n'Play", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).NotTo(ContainElement(HaveField("Name", "node_modules")))
+			})
+		})
+
+		context("when .yarnrc.yml sets the node-modules linker", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", ".yarnrc.yml"), []byte("nodeLinker: node-modules\n"), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", ".pnp.cjs"), nil, 0600)).To(Succeed())
+			})
+
+			it("requires node_modules", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(ContainElement(HaveField("Name", "node_modules")))
+			})
+		})
+
+		context("when there is no .yarnrc.yml but there is a .pnp.cjs", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "custom", ".yarnrc.yml"))).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", ".pnp.cjs"), nil, 0600)).To(Succeed())
+			})
+
+			it("does not require node_modules", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).NotTo(ContainElement(HaveField("Name", "node_modules")))
+			})
+		})
+	})
+
+	context("when there is no start script", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+				"scripts": {
+					"serve": "node server.js"
+				}
+			}`), 0600)).To(Succeed())
+
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "yarn.lock"), nil, 0600)).To(Succeed())
+		})
+
 		it("fails detection", func() {
 			_, err := detect(packit.DetectContext{
 				WorkingDir: workingDir,
 			})
-			Expect(err).To(MatchError(packit.Fail.WithMessage("no 'yarn.lock' found in the project path %s", filepath.Join(workingDir, "custom"))))
+			Expect(err).To(MatchError(packit.Fail.WithMessage(yarnstart.NoStartScriptError)))
+		})
+
+		context("and package.json has a main field that points at a file", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+					"main": "lib/app.js"
+				}`), 0600)).To(Succeed())
+				Expect(os.Mkdir(filepath.Join(workingDir, "custom", "lib"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "lib", "app.js"), nil, 0600)).To(Succeed())
+			})
+
+			it("detects", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(HaveLen(3))
+			})
+		})
+
+		context("and package.json has a single bin entry that points at a file", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+					"name": "@some/server",
+					"bin": "cli.js"
+				}`), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "cli.js"), nil, 0600)).To(Succeed())
+			})
+
+			it("detects", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(HaveLen(3))
+			})
+		})
+
+		context("and there is a default entrypoint", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "server.mjs"), nil, 0600)).To(Succeed())
+			})
+
+			it("detects", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(HaveLen(3))
+			})
+
+			context("and BP_YARN_START_SCRIPT names a script that does not exist", func() {
+				it.Before(func() {
+					t.Setenv("BP_YARN_START_SCRIPT", "start:prod")
+				})
+
+				it("fails detection", func() {
+					_, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).To(MatchError(packit.Fail.WithMessage(`no "start:prod" script in package.json`)))
+				})
+			})
+		})
+
+		context("and BP_YARN_START_SCRIPT names a script that exists", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_SCRIPT", "serve")
+			})
+
+			it("detects", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(HaveLen(3))
+			})
+		})
+
+		context("and BP_YARN_START_SCRIPT names a script that does not exist", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_SCRIPT", "start:prod")
+			})
+
+			it("fails detection", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(packit.Fail.WithMessage(`no "start:prod" script in package.json`)))
+			})
 		})
 	})
 
-	context("failure cases", func() {
-		context("the workspace directory cannot be accessed", func() {
+	context("when BP_YARN_START_WORKSPACE is set", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+				"workspaces": ["packages/*"]
+			}`), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "yarn.lock"), nil, 0600)).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(workingDir, "custom", "packages", "api"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "packages", "api", "package.json"), []byte(`{
+				"name": "@acme/api",
+				"scripts": {
+					"start": "node server.js"
+				}
+			}`), 0600)).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(workingDir, "custom", "packages", "lib"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "packages", "lib", "package.json"), []byte(`{
+				"name": "@acme/lib"
+			}`), 0600)).To(Succeed())
+		})
+
+		context("and the workspace has a start script", func() {
 			it.Before(func() {
-				Expect(os.Chmod(workingDir, 0000)).To(Succeed())
+				t.Setenv("BP_YARN_START_WORKSPACE", "@acme/api")
 			})
 
-			it.After(func() {
-				Expect(os.Chmod(workingDir, os.ModePerm)).To(Succeed())
+			it("detects without a start script in the root package.json", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(HaveLen(3))
 			})
+		})
 
-			it("returns an error", func() {
+		context("and the workspace has no start script or entrypoint", func() {
+			it.Before(func() {
+				t.Setenv("BP_YARN_START_WORKSPACE", "@acme/lib")
+			})
+
+			it("fails detection", func() {
 				_, err := detect(packit.DetectContext{
 					WorkingDir: workingDir,
 				})
-				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				Expect(err).To(MatchError(packit.Fail.WithMessage(yarnstart.NoStartScriptError)))
 			})
 		})
 
-		context("when the project path cannot be found", func() {
+		context("and there is no such workspace", func() {
 			it.Before(func() {
-				t.Setenv("BP_NODE_PROJECT_PATH", "does-not-exist")
+				t.Setenv("BP_YARN_START_WORKSPACE", "@acme/missing")
 			})
 
-			it("returns an error", func() {
+			it("fails detection", func() {
 				_, err := detect(packit.DetectContext{
 					WorkingDir: workingDir,
 				})
-				Expect(err).To(MatchError(ContainSubstring("could not find project path")))
+				Expect(err).To(MatchError(packit.Fail.WithMessage("no workspace named %q found in %s", "@acme/missing", filepath.Join(workingDir, "custom"))))
+			})
+		})
+	})
+
+	context("when BP_YARN_START_WORKSPACES is true", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+				"workspaces": ["packages/*"]
+			}`), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "yarn.lock"), nil, 0600)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(workingDir, "custom", "packages", "api"), os.ModePerm)).To(Succeed())
+
+			t.Setenv("BP_YARN_START_WORKSPACES", "true")
+		})
+
+		context("and a workspace has a start script", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "packages", "api", "package.json"), []byte(`{
+					"name": "@acme/api",
+					"scripts": {
+						"start": "node server.js"
+					}
+				}`), 0600)).To(Succeed())
+			})
+
+			it("detects without a start script in the root package.json", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(HaveLen(3))
+			})
+
+			context("and BP_LIVE_RELOAD_ENABLED is true", func() {
+				it.Before(func() {
+					t.Setenv("BP_LIVE_RELOAD_ENABLED", "true")
+				})
+
+				it("does not require watchexec, since there is no web process to reload", func() {
+					result, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Plan.Requires).To(HaveLen(3))
+					Expect(result.Plan.Requires).NotTo(ContainElement(HaveField("Name", "watchexec")))
+				})
+			})
+		})
+
+		context("and no workspace has a start script", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "packages", "api", "package.json"), []byte(`{
+					"name": "@acme/api"
+				}`), 0600)).To(Succeed())
+			})
+
+			it("fails detection", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(packit.Fail.WithMessage(yarnstart.NoStartScriptError)))
+			})
+		})
+	})
+
+	context("when the project path is a workspace member without a yarn.lock", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "package.json"), []byte(`{
+				"workspaces": ["custom"]
+			}`), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "yarn.lock"), nil, 0600)).To(Succeed())
+
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+				"scripts": {
+					"start": "node server.js"
+				}
+			}`), 0600)).To(Succeed())
+		})
+
+		it("detects using the yarn.lock of the workspace root and records the root", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan).To(Equal(packit.BuildPlan{
+				Requires: []packit.BuildPlanRequirement{
+					{
+						Name: "node",
+						Metadata: map[string]interface{}{
+							"launch":         true,
+							"workspace-root": workingDir,
+						},
+					},
+					{
+						Name: "yarn",
+						Metadata: map[string]interface{}{
+							"launch":         false,
+							"workspace-root": workingDir,
+						},
+					},
+					{
+						Name: "node_modules",
+						Metadata: map[string]interface{}{
+							"launch":         true,
+							"workspace-root": workingDir,
+						},
+					},
+				},
+			}))
+		})
+
+		context("and the workspace patterns of the root do not match it", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "package.json"), []byte(`{
+					"workspaces": ["packages/*"]
+				}`), 0600)).To(Succeed())
+			})
+
+			it("fails detection", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(packit.Fail.WithMessage("no 'yarn.lock' found in the project path %s", filepath.Join(workingDir, "custom"))))
+			})
+		})
+	})
+
+	context("when the project declares its package manager", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "yarn.lock"), nil, 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package-lock.json"), nil, 0600)).To(Succeed())
+		})
+
+		context("and it is yarn", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+					"packageManager": "yarn@4.1.0",
+					"scripts": {
+						"start": "node server.js"
+					}
+				}`), 0600)).To(Succeed())
+			})
+
+			it("detects", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(HaveLen(3))
+			})
+		})
+
+		context("and it is another package manager", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+					"packageManager": "pnpm@8.15.0+sha256.abc",
+					"scripts": {
+						"start": "node server.js"
+					}
+				}`), 0600)).To(Succeed())
+			})
+
+			it("fails detection", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(packit.Fail.WithMessage(`package.json declares "pnpm@8.15.0+sha256.abc" as its package manager: set BP_NODE_PACKAGE_MANAGER=yarn to use Yarn anyway`)))
+			})
+
+			context("and BP_NODE_PACKAGE_MANAGER is yarn", func() {
+				it.Before(func() {
+					t.Setenv("BP_NODE_PACKAGE_MANAGER", "yarn")
+				})
+
+				it("detects", func() {
+					result, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Plan.Requires).To(HaveLen(3))
+				})
+			})
+		})
+	})
+
+	context("when the lockfile of another package manager sits next to yarn.lock", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+				"scripts": {
+					"start": "node server.js"
+				}
+			}`), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "yarn.lock"), nil, 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "pnpm-lock.yaml"), nil, 0600)).To(Succeed())
+		})
+
+		it("fails detection and names both lockfiles", func() {
+			_, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).To(MatchError(packit.Fail.WithMessage("found both 'yarn.lock' and 'pnpm-lock.yaml' in %s: set BP_NODE_PACKAGE_MANAGER to choose the package manager", filepath.Join(workingDir, "custom"))))
+		})
+
+		context("and BP_NODE_PACKAGE_MANAGER is yarn", func() {
+			it.Before(func() {
+				t.Setenv("BP_NODE_PACKAGE_MANAGER", "yarn")
+			})
+
+			it("detects", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires).To(HaveLen(3))
+			})
+		})
+	})
+
+	context("when BP_NODE_PACKAGE_MANAGER names another package manager", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+				"scripts": {
+					"start": "node server.js"
+				}
+			}`), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "yarn.lock"), nil, 0600)).To(Succeed())
+			t.Setenv("BP_NODE_PACKAGE_MANAGER", "npm")
+		})
+
+		it("fails detection", func() {
+			_, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).To(MatchError(packit.Fail.WithMessage("BP_NODE_PACKAGE_MANAGER is set to npm")))
+		})
+	})
+
+	context("when there is no yarn.lock but there is the lockfile of another package manager", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "pnpm-lock.yaml"), nil, 0600)).To(Succeed())
+		})
+
+		it("fails detection and names the lockfile", func() {
+			_, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).To(MatchError(packit.Fail.WithMessage("no 'yarn.lock' found in the project path %s: found 'pnpm-lock.yaml' instead, which belongs to pnpm", filepath.Join(workingDir, "custom"))))
+		})
+	})
+
+	context("when package.json declares engine versions", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+				"engines": {
+					"node": ">=18 <21",
+					"yarn": "^1.22.0"
+				},
+				"scripts": {
+					"start": "node server.js"
+				}
+			}`), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "yarn.lock"), nil, 0600)).To(Succeed())
+		})
+
+		it("requires those versions", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires[:2]).To(Equal([]packit.BuildPlanRequirement{
+				{
+					Name: "node",
+					Metadata: map[string]interface{}{
+						"launch":         true,
+						"version":        ">=18 <21",
+						"version-source": "package.json",
+					},
+				},
+				{
+					Name: "yarn",
+					Metadata: map[string]interface{}{
+						"launch":         false,
+						"version":        "^1.22.0",
+						"version-source": "package.json",
+					},
+				},
+			}))
+		})
+
+		context("and the packageManager field names a yarn version", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+					"packageManager": "yarn@4.1.0+sha512.abc",
+					"engines": {
+						"yarn": ">=4"
+					},
+					"scripts": {
+						"start": "node server.js"
+					}
+				}`), 0600)).To(Succeed())
+			})
+
+			it("requires the yarn version of the packageManager field", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires[1]).To(Equal(packit.BuildPlanRequirement{
+					Name: "yarn",
+					Metadata: map[string]interface{}{
+						"launch":         false,
+						"version":        "4.1.0",
+						"version-source": "packageManager",
+					},
+				}))
+			})
+		})
+
+		context("and the engines field is malformed", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+					"engines": ["node >= 18"],
+					"scripts": {
+						"start": "node server.js"
+					}
+				}`), 0600)).To(Succeed())
+			})
+
+			it("ignores it", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Requires[0].Metadata).To(Equal(map[string]interface{}{
+					"launch": true,
+				}))
+			})
+		})
+	})
+
+	context("when a workspace member inherits engine versions from the workspace root", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "package.json"), []byte(`{
+				"workspaces": ["custom"],
+				"packageManager": "yarn@3.6.4",
+				"engines": {
+					"node": "20.x"
+				}
+			}`), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "yarn.lock"), nil, 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+				"scripts": {
+					"start": "node server.js"
+				}
+			}`), 0600)).To(Succeed())
+		})
+
+		it("requires the versions of the root", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires[0].Metadata).To(HaveKeyWithValue("version", "20.x"))
+			Expect(result.Plan.Requires[1].Metadata).To(HaveKeyWithValue("version", "3.6.4"))
+		})
+	})
+
+	context("when deciding whether yarn is needed at launch", func() {
+		var yarnLaunch = func() interface{} {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires[1].Name).To(Equal("yarn"))
+			return result.Plan.Requires[1].Metadata.(map[string]interface{})["launch"]
+		}
+
+		var writePackageJSON = func(content string) {
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(content), 0600)).To(Succeed())
+		}
+
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "custom", "yarn.lock"), nil, 0600)).To(Succeed())
+		})
+
+		it("requires yarn at launch only when a script invokes it", func() {
+			for _, scripts := range []struct {
+				json     string
+				expected bool
+			}{
+				{json: `{"start": "node server.js"}`, expected: false},
+				{json: `{"start": "node --require ./yarn-hooks.js server.js"}`, expected: false},
+				{json: `{"start": "test -f yarn.lock && node server.js"}`, expected: false},
+				{json: `{"start": "yarn node server.js"}`, expected: true},
+				{json: `{"start": "NODE_ENV=production yarnpkg run serve"}`, expected: true},
+				{json: `{"prestart": "yarn build", "start": "node server.js"}`, expected: true},
+				{json: `{"start": "node server.js", "poststart": "echo done;yarn cleanup"}`, expected: true},
+				{json: `{"start": "concurrently \"yarn api\" \"yarn web\""}`, expected: true},
+				{json: `{"start": "yarn run serve", "serve": "node server.js"}`, expected: false},
+				{json: `{"start": "yarn run serve", "serve": "yarn node server.js"}`, expected: true},
+			} {
+				writePackageJSON(fmt.Sprintf(`{"scripts": %s}`, scripts.json))
+				Expect(yarnLaunch()).To(Equal(scripts.expected), scripts.json)
+			}
+		})
+
+		context("when an additional process runs yarn", func() {
+			it.Before(func() {
+				writePackageJSON(`{
+					"scripts": {
+						"start": "node server.js",
+						"queue:work": "yarn worker"
+					}
+				}`)
+				t.Setenv("BP_YARN_START_PROCESSES", "worker=queue:work")
+			})
+
+			it("requires yarn at launch", func() {
+				Expect(yarnLaunch()).To(BeTrue())
+			})
+		})
+
+		context("when a workspace process runs yarn", func() {
+			it.Before(func() {
+				writePackageJSON(`{
+					"workspaces": ["packages/*"]
+				}`)
+				Expect(os.MkdirAll(filepath.Join(workingDir, "custom", "packages", "api"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "packages", "api", "package.json"), []byte(`{
+					"scripts": {
+						"start": "yarn dlx serve"
+					}
+				}`), 0600)).To(Succeed())
+				t.Setenv("BP_YARN_START_WORKSPACES", "true")
+			})
+
+			it("requires yarn at launch", func() {
+				Expect(yarnLaunch()).To(BeTrue())
+			})
+		})
+
+		context("when the start script runs the script of a workspace", func() {
+			it.Before(func() {
+				writePackageJSON(`{
+					"workspaces": ["packages/*"],
+					"scripts": {
+						"start": "yarn workspace @acme/api start"
+					}
+				}`)
+				Expect(os.MkdirAll(filepath.Join(workingDir, "custom", "packages", "api"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "packages", "api", "package.json"), []byte(`{
+					"name": "@acme/api",
+					"scripts": {
+						"start": "node server.js"
+					}
+				}`), 0600)).To(Succeed())
+			})
+
+			it("does not require yarn at launch", func() {
+				Expect(yarnLaunch()).To(BeFalse())
+			})
+		})
+
+		context("when inlining scripts is turned off", func() {
+			it.Before(func() {
+				writePackageJSON(`{"scripts": {"start": "yarn run serve", "serve": "node server.js"}}`)
+				t.Setenv("BP_YARN_START_INLINE_SCRIPTS", "false")
+			})
+
+			it("requires yarn at launch for the references", func() {
+				Expect(yarnLaunch()).To(BeTrue())
+			})
+		})
+
+		context("when the processes launch through yarn", func() {
+			it.Before(func() {
+				writePackageJSON(`{"scripts": {"start": "node server.js"}}`)
+			})
+
+			it("requires yarn at launch", func() {
+				t.Setenv("BP_YARN_START_MODE", "yarn")
+				Expect(yarnLaunch()).To(BeTrue())
+
+				t.Setenv("BP_YARN_START_MODE", "auto")
+				Expect(yarnLaunch()).To(BeFalse())
+
+				writePackageJSON(`{"packageManager": "yarn@4.1.0", "scripts": {"start": "node server.js"}}`)
+				Expect(yarnLaunch()).To(BeTrue())
+			})
+		})
+
+		context("when BP_YARN_START_REQUIRE_YARN is set", func() {
+			it("overrides the analysis of the scripts", func() {
+				writePackageJSON(`{"scripts": {"start": "node server.js"}}`)
+				t.Setenv("BP_YARN_START_REQUIRE_YARN", "true")
+				Expect(yarnLaunch()).To(BeTrue())
+
+				writePackageJSON(`{"scripts": {"start": "yarn node server.js"}}`)
+				t.Setenv("BP_YARN_START_REQUIRE_YARN", "false")
+				Expect(yarnLaunch()).To(BeFalse())
+			})
+		})
+	})
+
+	context("when there is no yarn.lock", func() {
+		it("fails detection", func() {
+			_, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).To(MatchError(packit.Fail.WithMessage("no 'yarn.lock' found in the project path %s", filepath.Join(workingDir, "custom"))))
+		})
+	})
+
+	context("failure cases", func() {
+		context("the workspace directory cannot be accessed", func() {
+			it.Before(func() {
+				Expect(os.Chmod(workingDir, 0000)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Chmod(workingDir, os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+
+		context("when the project path cannot be found", func() {
+			it.Before(func() {
+				t.Setenv("BP_NODE_PROJECT_PATH", "does-not-exist")
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("could not find project path")))
+			})
+		})
+
+		context("when BP_NODE_PACKAGE_MANAGER is set to an invalid value", func() {
+			it.Before(func() {
+				t.Setenv("BP_NODE_PACKAGE_MANAGER", "bun")
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError("failed to parse BP_NODE_PACKAGE_MANAGER value bun: expected one of npm, pnpm, yarn"))
+			})
+		})
+
+		context("when BP_YARN_START_REQUIRE_YARN is set to an invalid value", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "package.json"), []byte(`{
+					"scripts": {
+						"start": "node server.js"
+					}
+				}`), 0600)).To(Succeed())
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "custom", "yarn.lock"), nil, 0600)).To(Succeed())
+				t.Setenv("BP_YARN_START_REQUIRE_YARN", "not-a-bool")
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to parse BP_YARN_START_REQUIRE_YARN value not-a-bool")))
 			})
 		})
 
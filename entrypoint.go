@@ -0,0 +1,101 @@
+package yarnstart
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// entrypointFiles are the files that are started, in order of preference,
+// when package.json has neither a main field nor a single bin entry that
+// points at an existing file.
+var entrypointFiles = []string{"server.js", "index.js", "server.mjs"}
+
+// An entrypoint is a file that is run with node when package.json has no
+// start script.
+type entrypoint struct {
+	// Path is the location of the file relative to the project path.
+	Path string
+
+	// Source describes where the entrypoint was found.
+	Source string
+
+	// Module indicates whether node loads the file as an ES module.
+	Module bool
+}
+
+// findEntrypoint returns the first of the following that exists in the
+// project path: the file named by the main field of package.json, the file
+// named by its only bin entry, or one of the entrypointFiles.
+func findEntrypoint(projectPath string, pkg packageJSON) (entrypoint, bool, error) {
+	candidates := []entrypoint{}
+	if pkg.Main != "" {
+		candidates = append(candidates, entrypoint{Path: pkg.Main, Source: `the "main" field`})
+	}
+
+	bins, err := pkg.bins()
+	if err != nil {
+		return entrypoint{}, false, err
+	}
+
+	if len(bins) == 1 {
+		for name, path := range bins {
+			candidates = append(candidates, entrypoint{Path: path, Source: fmt.Sprintf("the %q bin entry", name)})
+		}
+	}
+
+	for _, file := range entrypointFiles {
+		candidates = append(candidates, entrypoint{Path: file, Source: "the default entrypoints"})
+	}
+
+	for _, candidate := range candidates {
+		path, ok, err := resolveEntrypoint(projectPath, candidate.Path)
+		if err != nil {
+			return entrypoint{}, false, err
+		}
+
+		if ok {
+			candidate.Path = path
+			candidate.Module = isModule(path, pkg.Type)
+			return candidate, true, nil
+		}
+	}
+
+	return entrypoint{}, false, nil
+}
+
+// resolveEntrypoint resolves path the way node resolves a main field: as a
+// file, as a file with a .js extension or as a directory with an index.js.
+func resolveEntrypoint(projectPath, path string) (string, bool, error) {
+	path = filepath.Clean(path)
+	for _, candidate := range []string{path, path + ".js", filepath.Join(path, "index.js")} {
+		info, err := os.Stat(filepath.Join(projectPath, candidate))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+				continue
+			}
+			return "", false, fmt.Errorf("failed to stat entrypoint: %w", err)
+		}
+
+		if info.Mode().IsRegular() {
+			return candidate, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// isModule reports whether node loads the file at path as an ES module, which
+// depends on the type field of package.json for .js files.
+func isModule(path, packageType string) bool {
+	switch filepath.Ext(path) {
+	case ".mjs":
+		return true
+	case ".cjs":
+		return false
+	default:
+		return packageType == "module"
+	}
+}
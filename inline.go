@@ -0,0 +1,406 @@
+package yarnstart
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// yarnCommands are the commands built into Yarn, which take precedence over
+// scripts of the same name when they are run as yarn <name>.
+var yarnCommands = []string{
+	"add", "audit", "autoclean", "bin", "cache", "check", "config",
+	"constraints", "create", "dedupe", "dlx", "exec", "explain",
+	"generate-lock-entry", "global", "help", "import", "info", "init",
+	"install", "licenses", "link", "list", "login", "logout", "node", "npm",
+	"outdated", "owner", "pack", "patch", "patch-commit", "plugin", "policies",
+	"publish", "rebuild", "remove", "run", "search", "set", "stage", "tag",
+	"team", "unlink", "unplug", "up", "upgrade", "upgrade-interactive",
+	"version", "versions", "why", "workspace", "workspaces",
+}
+
+// stateBuiltins are the shell builtins that change the state of the shell
+// they run in, such as its working directory or environment.
+var stateBuiltins = []string{
+	"cd", "pushd", "popd", "export", "unset", "set", "source", ".", "alias",
+	"unalias", "umask", "ulimit", "shopt", "exec", "exit", "trap", "readonly",
+	"declare", "typeset", "local", "eval",
+}
+
+// checkInlineScriptsEnabled reports whether references to other scripts are
+// expanded, which can be turned off with BP_YARN_START_INLINE_SCRIPTS.
+func checkInlineScriptsEnabled() (bool, error) {
+	return parseBoolEnv("BP_YARN_START_INLINE_SCRIPTS", true)
+}
+
+// A scriptInliner expands the references to other scripts in the scripts of a
+// project and of its workspaces, so that no further yarn process has to start
+// at launch.
+type scriptInliner struct {
+	enabled bool
+	root    string
+	rootPkg packageJSON
+
+	workspaces []workspace
+	found      bool
+}
+
+// A scriptFrame is a script of the package at Path.
+type scriptFrame struct {
+	Path    string
+	Package packageJSON
+	Script  string
+}
+
+// newScriptInliner returns an inliner for the project whose workspace root is
+// at root.
+func newScriptInliner(root string, rootPkg packageJSON) (*scriptInliner, error) {
+	enabled, err := checkInlineScriptsEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	return &scriptInliner{
+		enabled: enabled,
+		root:    root,
+		rootPkg: rootPkg,
+	}, nil
+}
+
+// scriptSegments returns the commands that Yarn runs for the named script of
+// the package at path: the script itself, or fallback when it does not exist,
+// between its pre and post scripts. When inlining is enabled, commands such
+// as yarn run <script> or yarn workspace <name> <script> are replaced by the
+// commands of the script they refer to.
+func (in *scriptInliner) scriptSegments(path string, pkg packageJSON, name, fallback string) ([]string, error) {
+	segments := pkg.lifecycleScripts(name, fallback)
+	if !in.enabled {
+		return segments, nil
+	}
+
+	return in.inlineSegments(segments, []scriptFrame{{Path: path, Package: pkg, Script: name}})
+}
+
+// inlineSegments expands the references to other scripts in each of the
+// segments. The stack holds the scripts being expanded, to detect cycles.
+func (in *scriptInliner) inlineSegments(segments []string, stack []scriptFrame) ([]string, error) {
+	current := stack[len(stack)-1]
+
+	var inlined []string
+	for _, segment := range segments {
+		commands := splitAndList(segment)
+
+		var found bool
+		references := make([][]scriptFrame, len(commands))
+		for i, command := range commands {
+			targets, err := in.scriptReferences(current, command)
+			if err != nil {
+				return nil, err
+			}
+
+			references[i] = targets
+			found = found || len(targets) > 0
+		}
+
+		if !found {
+			inlined = append(inlined, segment)
+			continue
+		}
+
+		for i, command := range commands {
+			if len(references[i]) == 0 {
+				inlined = append(inlined, command)
+				continue
+			}
+
+			for _, target := range references[i] {
+				if slices.ContainsFunc(stack, target.is) {
+					var labels []string
+					for _, frame := range append(stack, target) {
+						labels = append(labels, frame.label(stack[0].Path))
+					}
+					return nil, fmt.Errorf("failed to inline package.json scripts: cycle %s", strings.Join(labels, " -> "))
+				}
+
+				expanded, err := in.inlineSegments(target.Package.lifecycleScripts(target.Script, ""), append(slices.Clone(stack), target))
+				if err != nil {
+					return nil, err
+				}
+
+				// Yarn runs every script in a shell of its own, from the directory
+				// of its package, so a script that changes the state of its shell
+				// is kept apart in a subshell, as is the script of another
+				// workspace, or one that follows a command which may have left
+				// the directory of the package, such as the cd in
+				// cd dist && yarn run serve.
+				switch {
+				case target.Path != current.Path || !isolated(commands[:i]):
+					inlined = append(inlined, "( "+strings.Join(append([]string{shellJoin("cd", target.Path)}, expanded...), " && ")+" )")
+				case isolated(expanded):
+					inlined = append(inlined, expanded...)
+				default:
+					inlined = append(inlined, "( "+strings.Join(expanded, " && ")+" )")
+				}
+			}
+		}
+	}
+
+	return inlined, nil
+}
+
+// dispatch returns the script of another workspace when the named script does
+// nothing but run it, as "start": "yarn workspace @acme/api start" does.
+func (in *scriptInliner) dispatch(path string, pkg packageJSON, name string) (scriptFrame, bool, error) {
+	if !in.enabled {
+		return scriptFrame{}, false, nil
+	}
+
+	scripts := pkg.lifecycleScripts(name, "")
+	if len(scripts) != 1 || scripts[0] == "" {
+		return scriptFrame{}, false, nil
+	}
+
+	commands := splitAndList(scripts[0])
+	if len(commands) != 1 {
+		return scriptFrame{}, false, nil
+	}
+
+	targets, err := in.workspaceReferences(scriptFrame{Path: path, Package: pkg, Script: name}, commands[0])
+	if err != nil {
+		return scriptFrame{}, false, err
+	}
+
+	if len(targets) != 1 || targets[0].Path == path {
+		return scriptFrame{}, false, nil
+	}
+
+	return targets[0], true, nil
+}
+
+// scriptReferences returns the scripts that command runs, either from the
+// package of the current script or from the workspaces of the project.
+func (in *scriptInliner) scriptReferences(current scriptFrame, command string) ([]scriptFrame, error) {
+	if name, ok := current.Package.scriptReference(command); ok {
+		return []scriptFrame{{Path: current.Path, Package: current.Package, Script: name}}, nil
+	}
+
+	return in.workspaceReferences(current, command)
+}
+
+// workspaceReferences returns the scripts that command runs when it is
+// yarn workspace <name> [run] <script>, yarn workspaces run <script> or
+// yarn workspaces foreach [-A] [-v] [-i] run <script> in the current script.
+// Commands that run the scripts in parallel or in topological order are left
+// alone.
+func (in *scriptInliner) workspaceReferences(current scriptFrame, command string) ([]scriptFrame, error) {
+	fields, ok := splitCommand(command)
+	if !ok || len(fields) < 4 || fields[0] != Yarn {
+		return nil, nil
+	}
+
+	switch fields[1] {
+	case "workspace":
+		name, script := fields[2], fields[3]
+		switch {
+		case len(fields) == 5 && script == "run":
+			script = fields[4]
+		case len(fields) != 4 || slices.Contains(yarnCommands, script):
+			return nil, nil
+		}
+
+		members, err := in.members()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, member := range members {
+			if member.Package.Name == name && member.Package.hasScript(script) {
+				return []scriptFrame{{Path: member.Path, Package: member.Package, Script: script}}, nil
+			}
+		}
+
+	case "workspaces":
+		script, all, ok := parseWorkspacesRun(fields[2:])
+		if !ok {
+			return nil, nil
+		}
+
+		members, err := in.members()
+		if err != nil {
+			return nil, err
+		}
+
+		// Yarn 1 fails when a workspace lacks the script, whereas foreach skips
+		// such workspaces and includes the root workspace, unless that is where
+		// the same script is running already.
+		var targets []scriptFrame
+		root := scriptFrame{Path: in.root, Package: in.rootPkg, Script: script}
+		if !all && in.rootPkg.hasScript(script) && !root.is(current) {
+			targets = append(targets, root)
+		}
+
+		for _, member := range members {
+			if !member.Package.hasScript(script) {
+				if all {
+					return nil, nil
+				}
+				continue
+			}
+
+			targets = append(targets, scriptFrame{Path: member.Path, Package: member.Package, Script: script})
+		}
+
+		return targets, nil
+	}
+
+	return nil, nil
+}
+
+// parseWorkspacesRun returns the script run by the arguments of
+// yarn workspaces, and whether every workspace has to have that script, as it
+// does for the run command of Yarn 1.
+func parseWorkspacesRun(args []string) (string, bool, bool) {
+	if len(args) == 2 && args[0] == "run" {
+		return args[1], true, true
+	}
+
+	if len(args) < 3 || args[0] != "foreach" || args[len(args)-2] != "run" {
+		return "", false, false
+	}
+
+	for _, flag := range args[1 : len(args)-2] {
+		switch {
+		case flag == "--all" || flag == "--verbose" || flag == "--interlaced":
+		case len(flag) > 1 && flag[0] == '-' && strings.Trim(flag[1:], "Avi") == "":
+		default:
+			return "", false, false
+		}
+	}
+
+	return args[len(args)-1], false, true
+}
+
+// members returns the workspaces of the project, which are only looked up
+// once a script dispatches to them.
+func (in *scriptInliner) members() ([]workspace, error) {
+	if !in.found {
+		workspaces, err := findWorkspaces(in.root, in.rootPkg)
+		if err != nil {
+			return nil, err
+		}
+
+		in.workspaces, in.found = workspaces, true
+	}
+
+	return in.workspaces, nil
+}
+
+// is reports whether frame is the same script of the same package.
+func (frame scriptFrame) is(other scriptFrame) bool {
+	return frame.Path == other.Path && frame.Script == other.Script
+}
+
+// label names the script in error messages, along with its package when that
+// is not the package at origin.
+func (frame scriptFrame) label(origin string) string {
+	if frame.Path == origin {
+		return frame.Script
+	}
+
+	name := frame.Package.Name
+	if name == "" {
+		name = filepath.Base(frame.Path)
+	}
+
+	return fmt.Sprintf("%s %s", name, frame.Script)
+}
+
+// scriptReference returns the name of the script that command runs when it
+// is yarn run <script>, yarn <script>, npm run <script> or
+// npm run-script <script> without further arguments.
+func (pkg packageJSON) scriptReference(command string) (string, bool) {
+	fields, ok := splitCommand(command)
+	if !ok {
+		return "", false
+	}
+
+	var name string
+	switch {
+	case len(fields) == 3 && fields[0] == Yarn && fields[1] == "run":
+		name = fields[2]
+	case len(fields) == 2 && fields[0] == Yarn && !slices.Contains(yarnCommands, fields[1]):
+		name = fields[1]
+	case len(fields) == 3 && fields[0] == "npm" && (fields[1] == "run" || fields[1] == "run-script"):
+		name = fields[2]
+	default:
+		return "", false
+	}
+
+	if !pkg.hasScript(name) {
+		return "", false
+	}
+
+	return name, true
+}
+
+// isolated reports whether commands can run in the shell of the script that
+// refers to them without changing its state.
+func isolated(commands []string) bool {
+	for _, command := range commands {
+		fields, ok := splitCommand(command)
+		if !ok || slices.Contains(stateBuiltins, fields[0]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitAndList splits a script into the commands of its && list. A script
+// that uses any other operator or grouping is returned as a whole.
+func splitAndList(script string) []string {
+	var (
+		commands []string
+		start    int
+		quote    rune
+		escaped  bool
+	)
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escaped:
+			escaped = false
+
+		case r == '\\' && quote != '\'':
+			escaped = true
+
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+
+		case r == '\'' || r == '"':
+			quote = r
+
+		case r == '&' && i+1 < len(runes) && runes[i+1] == '&':
+			commands = append(commands, strings.TrimSpace(string(runes[start:i])))
+			start = i + 2
+			i++
+
+		case strings.ContainsRune("|&;()`\n", r) || (r == '$' && i+1 < len(runes) && runes[i+1] == '('):
+			return []string{script}
+		}
+	}
+
+	commands = append(commands, strings.TrimSpace(string(runes[start:])))
+	for _, command := range commands {
+		if command == "" {
+			return []string{script}
+		}
+	}
+
+	return commands
+}
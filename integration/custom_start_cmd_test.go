@@ -83,7 +83,12 @@ func testCustomStartCmd(t *testing.T, context spec.G, it spec.S) {
 			))
 			Expect(logs).To(ContainLines(
 				extenderBuildStr+"  Assigning launch processes:",
-				extenderBuildStr+`    web (default): bash -c echo "prestart" && echo "start" && node server.js && echo "poststart"`,
+				extenderBuildStr+`    web (default): /layers/paketo-buildpacks_yarn-start/launch-init/bin/launch-init bash -c echo "prestart" && echo "start" && node server.js && exec echo "poststart"`,
+				extenderBuildStr+`      PATH                 -> "/workspace/node_modules/.bin:$PATH"`,
+				extenderBuildStr+`      npm_lifecycle_event  -> "start"`,
+				extenderBuildStr+`      npm_lifecycle_script -> "echo "start" && node server.js"`,
+				extenderBuildStr+`      npm_package_name     -> "simple_app"`,
+				extenderBuildStr+`      npm_package_version  -> "0.0.0"`,
 				extenderBuildStr+"",
 			))
 
@@ -136,8 +141,18 @@ func testCustomStartCmd(t *testing.T, context spec.G, it spec.S) {
 					MatchRegexp(fmt.Sprintf(`%s%s \d+\.\d+\.\d+`, extenderBuildStr, settings.Buildpack.Name))))
 				Expect(logs).To(ContainLines(
 					extenderBuildStr+"  Assigning launch processes:",
-					extenderBuildStr+`    web (default): watchexec --restart --shell none --watch /workspace --ignore /workspace/package.json --ignore /workspace/yarn.lock --ignore /workspace/node_modules -- bash -c echo "prestart" && echo "start" && node server.js && echo "poststart"`,
-					extenderBuildStr+`    no-reload:     bash -c echo "prestart" && echo "start" && node server.js && echo "poststart"`,
+					extenderBuildStr+`    web (default): /layers/paketo-buildpacks_yarn-start/launch-init/bin/launch-init watchexec --restart --shell none --watch /workspace --ignore /workspace/package.json --ignore /workspace/yarn.lock --ignore /workspace/node_modules --ignore /workspace/**/node_modules --ignore /workspace/.git --ignore-file /workspace/.gitignore -- bash -c echo "prestart" && echo "start" && node server.js && exec echo "poststart"`,
+					extenderBuildStr+`      PATH                 -> "/workspace/node_modules/.bin:$PATH"`,
+					extenderBuildStr+`      npm_lifecycle_event  -> "start"`,
+					extenderBuildStr+`      npm_lifecycle_script -> "echo "start" && node server.js"`,
+					extenderBuildStr+`      npm_package_name     -> "simple_app"`,
+					extenderBuildStr+`      npm_package_version  -> "0.0.0"`,
+					extenderBuildStr+`    no-reload:     /layers/paketo-buildpacks_yarn-start/launch-init/bin/launch-init bash -c echo "prestart" && echo "start" && node server.js && exec echo "poststart"`,
+					extenderBuildStr+`      PATH                 -> "/workspace/node_modules/.bin:$PATH"`,
+					extenderBuildStr+`      npm_lifecycle_event  -> "start"`,
+					extenderBuildStr+`      npm_lifecycle_script -> "echo "start" && node server.js"`,
+					extenderBuildStr+`      npm_package_name     -> "simple_app"`,
+					extenderBuildStr+`      npm_package_version  -> "0.0.0"`,
 					extenderBuildStr+"",
 				))
 
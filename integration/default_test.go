@@ -81,7 +81,12 @@ func testDefault(t *testing.T, context spec.G, it spec.S) {
 
 			Expect(logs).To(ContainLines(
 				extenderBuildStr+"  Assigning launch processes:",
-				extenderBuildStr+"    web (default): bash -c node server.js",
+				extenderBuildStr+"    web (default): /layers/paketo-buildpacks_yarn-start/launch-init/bin/launch-init node server.js",
+				extenderBuildStr+`      PATH                 -> "/workspace/node_modules/.bin:$PATH"`,
+				extenderBuildStr+`      npm_lifecycle_event  -> "start"`,
+				extenderBuildStr+`      npm_lifecycle_script -> "node server.js"`,
+				extenderBuildStr+`      npm_package_name     -> "simple_app"`,
+				extenderBuildStr+`      npm_package_version  -> "0.0.0"`,
 				extenderBuildStr+"",
 			))
 
@@ -126,8 +131,18 @@ func testDefault(t *testing.T, context spec.G, it spec.S) {
 				))
 				Expect(logs).To(ContainLines(
 					extenderBuildStr+"  Assigning launch processes:",
-					extenderBuildStr+`    web (default): watchexec --restart --shell none --watch /workspace --ignore /workspace/package.json --ignore /workspace/yarn.lock --ignore /workspace/node_modules -- bash -c node server.js`,
-					extenderBuildStr+"    no-reload:     bash -c node server.js",
+					extenderBuildStr+`    web (default): /layers/paketo-buildpacks_yarn-start/launch-init/bin/launch-init watchexec --restart --shell none --watch /workspace --ignore /workspace/package.json --ignore /workspace/yarn.lock --ignore /workspace/node_modules --ignore /workspace/**/node_modules --ignore /workspace/.git --ignore-file /workspace/.gitignore -- node server.js`,
+					extenderBuildStr+`      PATH                 -> "/workspace/node_modules/.bin:$PATH"`,
+					extenderBuildStr+`      npm_lifecycle_event  -> "start"`,
+					extenderBuildStr+`      npm_lifecycle_script -> "node server.js"`,
+					extenderBuildStr+`      npm_package_name     -> "simple_app"`,
+					extenderBuildStr+`      npm_package_version  -> "0.0.0"`,
+					extenderBuildStr+"    no-reload:     /layers/paketo-buildpacks_yarn-start/launch-init/bin/launch-init node server.js",
+					extenderBuildStr+`      PATH                 -> "/workspace/node_modules/.bin:$PATH"`,
+					extenderBuildStr+`      npm_lifecycle_event  -> "start"`,
+					extenderBuildStr+`      npm_lifecycle_script -> "node server.js"`,
+					extenderBuildStr+`      npm_package_name     -> "simple_app"`,
+					extenderBuildStr+`      npm_package_version  -> "0.0.0"`,
 					extenderBuildStr+"",
 				))
 
@@ -1,6 +1,7 @@
 package integration_test
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -90,7 +91,12 @@ func testGracefulShutdown(t *testing.T, context spec.G, it spec.S) {
 
 			Expect(logs).To(ContainLines(
 				extenderBuildStr+"  Assigning launch processes:",
-				extenderBuildStr+"    web (default): bash -c node server.js",
+				extenderBuildStr+"    web (default): /layers/paketo-buildpacks_yarn-start/launch-init/bin/launch-init node server.js",
+				extenderBuildStr+`      PATH                 -> "/workspace/node_modules/.bin:$PATH"`,
+				extenderBuildStr+`      npm_lifecycle_event  -> "start"`,
+				extenderBuildStr+`      npm_lifecycle_script -> "node server.js"`,
+				extenderBuildStr+`      npm_package_name     -> "graceful_shutdown_app"`,
+				extenderBuildStr+`      npm_package_version  -> "0.0.0"`,
 				extenderBuildStr+"",
 			))
 
@@ -104,5 +110,77 @@ func testGracefulShutdown(t *testing.T, context spec.G, it spec.S) {
 
 			Eventually(cLogs).Should(ContainSubstring("echo from SIGTERM handler"))
 		})
+
+		context("when the start script has to run through bash", func() {
+			it.Before(func() {
+				var err error
+				source, err = occam.Source(filepath.Join("testdata", "graceful_shutdown_app"))
+				Expect(err).NotTo(HaveOccurred())
+
+				content, err := os.ReadFile(filepath.Join(source, "package.json"))
+				Expect(err).NotTo(HaveOccurred())
+
+				content = bytes.Replace(content, []byte(`"start": "node server.js"`), []byte(`"start": "node server.js; echo stopped"`), 1)
+				Expect(os.WriteFile(filepath.Join(source, "package.json"), content, 0600)).To(Succeed())
+
+				// The handler only finishes after bash has already exited on the
+				// forwarded SIGTERM.
+				content, err = os.ReadFile(filepath.Join(source, "server.js"))
+				Expect(err).NotTo(HaveOccurred())
+
+				content = bytes.Replace(content, []byte(`server.close();`), []byte(`setTimeout(() => { console.log('finished SIGTERM handler'); server.close(); }, 1000);`), 1)
+				Expect(os.WriteFile(filepath.Join(source, "server.js"), content, 0600)).To(Succeed())
+			})
+
+			it("forwards signals from the launch init to the node server", func() {
+				var err error
+				var logs fmt.Stringer
+				image, logs, err = pack.WithNoColor().Build.
+					WithExtensions(
+						settings.Extensions.UbiNodejsExtension.Online,
+					).
+					WithBuildpacks(
+						settings.Buildpacks.NodeEngine.Online,
+						settings.Buildpacks.Yarn.Online,
+						settings.Buildpacks.YarnInstall.Online,
+						settings.Buildpacks.YarnStart.Online,
+					).
+					WithPullPolicy(pullPolicy).
+					Execute(name, source)
+				Expect(err).NotTo(HaveOccurred(), logs.String())
+
+				Expect(logs).To(ContainLines(
+					extenderBuildStr+"  Assigning launch processes:",
+					extenderBuildStr+"    web (default): /layers/paketo-buildpacks_yarn-start/launch-init/bin/launch-init bash -c node server.js; echo stopped",
+					extenderBuildStr+`      PATH                 -> "/workspace/node_modules/.bin:$PATH"`,
+					extenderBuildStr+`      npm_lifecycle_event  -> "start"`,
+					extenderBuildStr+`      npm_lifecycle_script -> "node server.js; echo stopped"`,
+					extenderBuildStr+`      npm_package_name     -> "graceful_shutdown_app"`,
+					extenderBuildStr+`      npm_package_version  -> "0.0.0"`,
+					extenderBuildStr+"",
+				))
+
+				container, err = docker.Container.Run.
+					WithEnv(map[string]string{"PORT": "8080"}).
+					WithPublish("8080").
+					WithPublishAll().
+					Execute(image.ID)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(container).Should(BeAvailable())
+				Eventually(container).Should(Serve(ContainSubstring("Hello, World")))
+
+				Expect(docker.Container.Stop.Execute(container.ID)).To(Succeed())
+
+				cLogs := func() string {
+					containerLogs, err := docker.Container.Logs.Execute(container.ID)
+					Expect(err).NotTo(HaveOccurred())
+					return containerLogs.String()
+				}
+
+				Eventually(cLogs).Should(ContainSubstring("echo from SIGTERM handler"))
+				Eventually(cLogs).Should(ContainSubstring("finished SIGTERM handler"))
+			})
+		})
 	})
 }
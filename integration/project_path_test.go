@@ -83,7 +83,12 @@ func testProjectPath(t *testing.T, context spec.G, it spec.S) {
 
 			Expect(logs).To(ContainLines(
 				extenderBuildStr+"  Assigning launch processes:",
-				extenderBuildStr+`    web (default): bash -c cd /workspace/hello_world_server && echo "prehello" && echo "starthello" && node server.js && echo "posthello"`,
+				extenderBuildStr+`    web (default): /layers/paketo-buildpacks_yarn-start/launch-init/bin/launch-init bash -c echo "prehello" && echo "starthello" && node server.js && exec echo "posthello"`,
+				extenderBuildStr+`      PATH                 -> "/workspace/hello_world_server/node_modules/.bin:$PATH"`,
+				extenderBuildStr+`      npm_lifecycle_event  -> "start"`,
+				extenderBuildStr+`      npm_lifecycle_script -> "echo "starthello" && node server.js"`,
+				extenderBuildStr+`      npm_package_name     -> "simple_app"`,
+				extenderBuildStr+`      npm_package_version  -> "0.0.0"`,
 				extenderBuildStr+"",
 			))
 
@@ -138,8 +143,18 @@ func testProjectPath(t *testing.T, context spec.G, it spec.S) {
 
 				Expect(logs).To(ContainLines(
 					extenderBuildStr+"  Assigning launch processes:",
-					extenderBuildStr+`    web (default): watchexec --restart --shell none --watch /workspace/hello_world_server --ignore /workspace/hello_world_server/package.json --ignore /workspace/hello_world_server/yarn.lock --ignore /workspace/hello_world_server/node_modules -- bash -c cd /workspace/hello_world_server && echo "prehello" && echo "starthello" && node server.js && echo "posthello"`,
-					extenderBuildStr+`    no-reload:     bash -c cd /workspace/hello_world_server && echo "prehello" && echo "starthello" && node server.js && echo "posthello"`,
+					extenderBuildStr+`    web (default): /layers/paketo-buildpacks_yarn-start/launch-init/bin/launch-init watchexec --restart --shell none --watch /workspace/hello_world_server --ignore /workspace/hello_world_server/package.json --ignore /workspace/hello_world_server/yarn.lock --ignore /workspace/hello_world_server/node_modules --ignore /workspace/hello_world_server/**/node_modules --ignore /workspace/hello_world_server/.git -- bash -c echo "prehello" && echo "starthello" && node server.js && exec echo "posthello"`,
+					extenderBuildStr+`      PATH                 -> "/workspace/hello_world_server/node_modules/.bin:$PATH"`,
+					extenderBuildStr+`      npm_lifecycle_event  -> "start"`,
+					extenderBuildStr+`      npm_lifecycle_script -> "echo "starthello" && node server.js"`,
+					extenderBuildStr+`      npm_package_name     -> "simple_app"`,
+					extenderBuildStr+`      npm_package_version  -> "0.0.0"`,
+					extenderBuildStr+`    no-reload:     /layers/paketo-buildpacks_yarn-start/launch-init/bin/launch-init bash -c echo "prehello" && echo "starthello" && node server.js && exec echo "posthello"`,
+					extenderBuildStr+`      PATH                 -> "/workspace/hello_world_server/node_modules/.bin:$PATH"`,
+					extenderBuildStr+`      npm_lifecycle_event  -> "start"`,
+					extenderBuildStr+`      npm_lifecycle_script -> "echo "starthello" && node server.js"`,
+					extenderBuildStr+`      npm_package_name     -> "simple_app"`,
+					extenderBuildStr+`      npm_package_version  -> "0.0.0"`,
 					extenderBuildStr+"",
 				))
 
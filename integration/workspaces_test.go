@@ -78,9 +78,18 @@ func testWorkspaces(t *testing.T, context spec.G, it spec.S) {
 			Expect(logs).To(ContainLines(
 				MatchRegexp(fmt.Sprintf(`%s%s \d+\.\d+\.\d+`, extenderBuildStr, settings.Buildpack.Name))))
 
+			Expect(logs).To(ContainLines(
+				extenderBuildStr + `  Resolving the "start" script to the "start" script of workspace @sample/sample-app in packages/sample-app`,
+			))
+
 			Expect(logs).To(ContainLines(
 				extenderBuildStr+"  Assigning launch processes:",
-				extenderBuildStr+"    web (default): bash -c yarn workspace @sample/sample-app start",
+				extenderBuildStr+"    web (default): /layers/paketo-buildpacks_yarn-start/launch-init/bin/launch-init node index.js",
+				extenderBuildStr+`      PATH                 -> "/workspace/packages/sample-app/node_modules/.bin:/workspace/node_modules/.bin:$PATH"`,
+				extenderBuildStr+`      npm_lifecycle_event  -> "start"`,
+				extenderBuildStr+`      npm_lifecycle_script -> "node index.js"`,
+				extenderBuildStr+`      npm_package_name     -> "@sample/sample-app"`,
+				extenderBuildStr+`      npm_package_version  -> "1.0.0"`,
 				extenderBuildStr+"",
 			))
 
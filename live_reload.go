@@ -0,0 +1,424 @@
+package yarnstart
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2/fs"
+)
+
+// The live reload engines accepted by BP_LIVE_RELOAD_ENGINE.
+const (
+	liveReloadEngineWatchexec = "watchexec"
+	liveReloadEngineNode      = "node"
+)
+
+// liveReloadEngines are the values accepted by BP_LIVE_RELOAD_ENGINE.
+var liveReloadEngines = []string{liveReloadEngineWatchexec, liveReloadEngineNode}
+
+// nodeWatchIncompatibleOptions are the node options that node --watch
+// refuses, or that leave it without a script to watch.
+var nodeWatchIncompatibleOptions = []string{"-e", "--eval", "-p", "--print", "-i", "--interactive", "-c", "--check", "--test", "-"}
+
+// liveReloadSignals are the signals that BP_LIVE_RELOAD_SIGNAL accepts.
+var liveReloadSignals = []string{"SIGHUP", "SIGINT", "SIGQUIT", "SIGKILL", "SIGTERM", "SIGUSR1", "SIGUSR2"}
+
+// watchexecOnlyVariables are the variables that tune watchexec, which node
+// --watch has no equivalent for.
+var watchexecOnlyVariables = []string{
+	"BP_LIVE_RELOAD_IGNORE",
+	"BP_LIVE_RELOAD_SIGNAL",
+	"BP_LIVE_RELOAD_STOP_TIMEOUT",
+	"BP_LIVE_RELOAD_DEBOUNCE",
+	"BP_LIVE_RELOAD_POLL_INTERVAL",
+}
+
+// watchexecOnlyFlags are the boolean variables that turn on features which
+// only watchexec supports, and only count when they are true.
+var watchexecOnlyFlags = []string{
+	"BP_LIVE_RELOAD_POLL",
+	"BP_LIVE_RELOAD_INSTALL",
+}
+
+// liveReloadEngineOption returns the engine that BP_LIVE_RELOAD_ENGINE chooses
+// to reload the web process with.
+func liveReloadEngineOption() (string, error) {
+	engine := os.Getenv("BP_LIVE_RELOAD_ENGINE")
+	if engine == "" {
+		return liveReloadEngineWatchexec, nil
+	}
+
+	if !slices.Contains(liveReloadEngines, engine) {
+		return "", fmt.Errorf("failed to parse BP_LIVE_RELOAD_ENGINE value %s: expected one of %s", engine, strings.Join(liveReloadEngines, ", "))
+	}
+
+	return engine, nil
+}
+
+// liveReloadEngine returns the engine that reloads the web process, which runs
+// segments. That is node when BP_LIVE_RELOAD_ENGINE chooses it and node --watch
+// can reload the command, along with the words of its node command, and
+// watchexec otherwise, along with the reason why node could not be used when
+// BP_LIVE_RELOAD_ENGINE chooses it.
+func liveReloadEngine(segments []string) (string, []string, string, error) {
+	engine, err := liveReloadEngineOption()
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	if engine != liveReloadEngineNode {
+		return liveReloadEngineWatchexec, nil, "", nil
+	}
+
+	fields, reason := nodeWatchCommand(segments)
+	if reason != "" {
+		return liveReloadEngineWatchexec, nil, reason, nil
+	}
+
+	return liveReloadEngineNode, fields, "", nil
+}
+
+// nodeWatchCommand returns the words of the node command that segments
+// consist of, which node --watch can reload on its own, or the reason why it
+// cannot.
+func nodeWatchCommand(segments []string) ([]string, string) {
+	for _, name := range watchexecOnlyVariables {
+		if strings.TrimSpace(os.Getenv(name)) != "" {
+			return nil, fmt.Sprintf("node --watch does not support %s", name)
+		}
+	}
+
+	// An invalid value falls back to watchexec as well, which then reports it.
+	for _, name := range watchexecOnlyFlags {
+		if enabled, err := parseBoolEnv(name, false); enabled || err != nil {
+			return nil, fmt.Sprintf("node --watch does not support %s", name)
+		}
+	}
+
+	if len(segments) != 1 {
+		return nil, "the start command runs more than one command"
+	}
+
+	fields, ok := splitCommand(segments[0])
+	if !ok || fields[0] != Node {
+		return nil, "the start command is not a plain node command"
+	}
+
+	var script bool
+	for _, arg := range fields[1:] {
+		option, _, _ := strings.Cut(arg, "=")
+		if slices.Contains(nodeWatchIncompatibleOptions, option) {
+			return nil, fmt.Sprintf("node --watch does not support %s", option)
+		}
+
+		script = script || !strings.HasPrefix(arg, "-")
+	}
+
+	if !script {
+		return nil, "the start command does not name a script for node to run"
+	}
+
+	return fields, ""
+}
+
+// liveReloadIgnoreFiles are the ignore files of the project path that the
+// reloadable process honors.
+var liveReloadIgnoreFiles = []string{".gitignore", ".watchexecignore"}
+
+// watchexecArgs returns the arguments that make watchexec restart a command
+// whenever files in the project at projectPath change. Changes to the files
+// of the package manager, to the node_modules of any workspace, to the
+// patterns of BP_LIVE_RELOAD_IGNORE and to the files matched by the ignore
+// files of the project do not cause a restart, unless the files of the
+// package manager are among the given manifests, which are watched wherever
+// they are.
+func watchexecArgs(projectPath string, manifests []string) ([]string, error) {
+	watches, err := liveReloadWatchPaths(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := watchexecOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]string{"--restart", "--shell", "none"}, options...)
+	for _, watch := range watches {
+		args = append(args, "--watch", watch)
+	}
+
+	for _, manifest := range manifests {
+		if !slices.ContainsFunc(watches, func(watch string) bool { return isWithin(watch, manifest) }) {
+			args = append(args, "--watch", manifest)
+		}
+	}
+
+	root := globEscape(projectPath)
+	var ignores []string
+	if len(manifests) == 0 {
+		ignores = append(ignores, root+"/package.json", root+"/yarn.lock")
+	}
+
+	ignores = append(ignores,
+		root+"/"+NodeModules,
+		root+"/**/"+NodeModules,
+		root+"/.git",
+	)
+
+	for _, pattern := range splitGlobList(os.Getenv("BP_LIVE_RELOAD_IGNORE")) {
+		ignores = append(ignores, anchorGlob(root, pattern))
+	}
+
+	for _, ignore := range ignores {
+		args = append(args, "--ignore", ignore)
+	}
+
+	for _, name := range liveReloadIgnoreFiles {
+		path := filepath.Join(projectPath, name)
+		exists, err := fs.Exists(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", name, err)
+		}
+
+		if exists {
+			args = append(args, "--ignore-file", path)
+		}
+	}
+
+	return args, nil
+}
+
+// watchexecOptions returns the watchexec options chosen by
+// BP_LIVE_RELOAD_SIGNAL, BP_LIVE_RELOAD_STOP_TIMEOUT, BP_LIVE_RELOAD_DEBOUNCE,
+// BP_LIVE_RELOAD_POLL and BP_LIVE_RELOAD_POLL_INTERVAL.
+func watchexecOptions() ([]string, error) {
+	var options []string
+
+	if value := os.Getenv("BP_LIVE_RELOAD_SIGNAL"); value != "" {
+		signal := strings.ToUpper(value)
+		if !strings.HasPrefix(signal, "SIG") {
+			signal = "SIG" + signal
+		}
+
+		if !slices.Contains(liveReloadSignals, signal) {
+			return nil, fmt.Errorf("failed to parse BP_LIVE_RELOAD_SIGNAL value %s: expected one of %s", value, strings.Join(liveReloadSignals, ", "))
+		}
+
+		options = append(options, "--stop-signal", signal)
+	}
+
+	for _, option := range []struct {
+		Name string
+		Flag string
+	}{
+		{Name: "BP_LIVE_RELOAD_STOP_TIMEOUT", Flag: "--stop-timeout"},
+		{Name: "BP_LIVE_RELOAD_DEBOUNCE", Flag: "--debounce"},
+	} {
+		timeout, ok, err := parseDurationEnv(option.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			options = append(options, option.Flag, timeout)
+		}
+	}
+
+	poll, err := parseBoolEnv("BP_LIVE_RELOAD_POLL", false)
+	if err != nil {
+		return nil, err
+	}
+
+	interval, ok, err := parseDurationEnv("BP_LIVE_RELOAD_POLL_INTERVAL")
+	if err != nil {
+		return nil, err
+	}
+
+	// A poll interval implies polling, which is what file systems that do not
+	// report changes, such as bind mounts from Docker Desktop, need.
+	switch {
+	case ok:
+		options = append(options, "--poll", interval)
+	case poll:
+		options = append(options, "--poll")
+	}
+
+	return options, nil
+}
+
+// parseDurationEnv returns the positive duration that the named variable is
+// set to, in the milliseconds that watchexec accepts, and whether it is set at
+// all.
+func parseDurationEnv(name string) (string, bool, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return "", false, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to parse %s value %s: %w", name, value, err)
+	}
+
+	if duration.Milliseconds() < 1 {
+		return "", false, fmt.Errorf("failed to parse %s value %s: expected a duration of at least 1ms, such as 500ms or 2s", name, value)
+	}
+
+	return fmt.Sprintf("%dms", duration.Milliseconds()), true, nil
+}
+
+// liveReloadWatchPaths returns the paths matched by the patterns of
+// BP_LIVE_RELOAD_WATCH, relative to the project path, or the project path
+// itself when it is not set.
+func liveReloadWatchPaths(projectPath string) ([]string, error) {
+	value := os.Getenv("BP_LIVE_RELOAD_WATCH")
+	patterns := splitGlobList(value)
+	if len(patterns) == 0 {
+		return []string{projectPath}, nil
+	}
+
+	var paths []string
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(projectPath, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse BP_LIVE_RELOAD_WATCH value %s: %w", value, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("failed to parse BP_LIVE_RELOAD_WATCH value %s: %s matches no files", value, pattern)
+		}
+
+		paths = append(paths, matches...)
+	}
+
+	return paths, nil
+}
+
+// splitGlobList splits a colon-separated list of glob patterns, as accepted by
+// BP_LIVE_RELOAD_IGNORE and BP_LIVE_RELOAD_WATCH.
+func splitGlobList(value string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ":") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns
+}
+
+// anchorGlob makes an ignore pattern relative to the project at root, which is
+// already glob-escaped. As in a .gitignore, a pattern without a slash other
+// than a trailing one matches at any depth, and any other pattern matches
+// from the project path.
+func anchorGlob(root, pattern string) string {
+	pattern = strings.TrimSuffix(pattern, "/")
+	if !strings.Contains(pattern, "/") {
+		return root + "/**/" + pattern
+	}
+
+	return root + "/" + strings.TrimPrefix(pattern, "/")
+}
+
+// isWithin reports whether path is dir or lies beneath it.
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func checkLiveReloadInstallEnabled() (bool, error) {
+	return parseBoolEnv("BP_LIVE_RELOAD_INSTALL", false)
+}
+
+// yarnInstallCommand returns the command that reinstalls the dependencies
+// from the offline cache first. Yarn Berry always prefers its cache, whereas
+// Yarn 1 only uses its offline mirror when told to, and falls back to the
+// registry when a package is missing from it.
+func yarnInstallCommand(berry bool) string {
+	if berry {
+		return shellJoin(Yarn, "install")
+	}
+
+	return shellJoin(Yarn, "install", "--offline") + " || " + shellJoin(Yarn, "install", "--prefer-offline")
+}
+
+// reinstallScript returns a bash script that runs install in dir when the
+// contents of the manifests differ from those in the snapshot file, updates
+// the snapshot, and then executes its arguments in place of itself. It reads
+// the files with bash alone, since the run image does not necessarily have any
+// other tools to do so. A failed install is logged and retried on the next
+// change, and the app starts regardless, so that a reinstall that cannot
+// write to node_modules does not keep the app down.
+func reinstallScript(dir string, manifests []string, snapshot, install string) string {
+	quoted := make([]string, len(manifests))
+	for i, manifest := range manifests {
+		quoted[i] = shellQuote(manifest)
+	}
+
+	return strings.Join([]string{
+		"#!/usr/bin/env bash",
+		"",
+		"set -u",
+		"",
+		"snapshot=" + shellQuote(snapshot),
+		"manifests=(" + strings.Join(quoted, " ") + ")",
+		"",
+		"read_manifests() {",
+		"  local manifest",
+		`  for manifest in "${manifests[@]}"; do`,
+		`    if [[ -f "${manifest}" ]]; then`,
+		`      printf '%s
+' "$(<"${manifest}")"`,
+		"    else",
+		`      printf '
+'`,
+		"    fi",
+		"  done",
+		"}",
+		"",
+		`saved=""`,
+		`if [[ -f "${snapshot}" ]]; then`,
+		`  saved="$(<"${snapshot}")"`,
+		"fi",
+		"",
+		`if [[ "$(read_manifests)" != "${saved}" ]]; then`,
+		`  echo "Reinstalling the dependencies, since package.json or yarn.lock changed" >&2`,
+		"  if (" + shellJoin("cd", dir) + " && { " + install + "; }); then",
+		`    read_manifests > "${snapshot}"`,
+		"  else",
+		`    echo "Failed to reinstall the dependencies, starting the app anyway" >&2`,
+		"  fi",
+		"fi",
+		"",
+		`exec "$@"`,
+		"",
+	}, "\n")
+}
+
+// manifestSnapshot returns the contents of the manifests in the form that the
+// reinstall script reads them in: each of them without its trailing newlines
+// and followed by a single one, or an empty line for one that does not exist.
+func manifestSnapshot(manifests []string) ([]byte, error) {
+	var snapshot []byte
+	for _, manifest := range manifests {
+		content, err := os.ReadFile(manifest)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(manifest), err)
+		}
+
+		snapshot = append(snapshot, bytes.TrimRight(content, "\n")...)
+		snapshot = append(snapshot, '\n')
+	}
+
+	return snapshot, nil
+}
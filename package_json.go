@@ -0,0 +1,196 @@
+package yarnstart
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2"
+)
+
+// invalidEnvironmentCharacters matches the characters that Yarn replaces with
+// underscores when it turns config keys into environment variable names.
+var invalidEnvironmentCharacters = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// packageJSON represents the parts of a package.json file that determine how
+// an application is started.
+type packageJSON struct {
+	Name       string            `json:"name"`
+	Version    string            `json:"version"`
+	Main       string            `json:"main"`
+	Type       string            `json:"type"`
+	Bin        json.RawMessage   `json:"bin"`
+	Scripts    map[string]string `json:"scripts"`
+	Workspaces json.RawMessage   `json:"workspaces"`
+
+	PackageManager string          `json:"packageManager"`
+	Engines        json.RawMessage `json:"engines"`
+	Config         json.RawMessage `json:"config"`
+}
+
+func parsePackageJSON(path string) (packageJSON, error) {
+	file, err := os.Open(filepath.Join(path, "package.json"))
+	if err != nil {
+		return packageJSON{}, err
+	}
+	defer file.Close()
+
+	var pkg packageJSON
+	err = json.NewDecoder(file).Decode(&pkg)
+	if err != nil {
+		return packageJSON{}, fmt.Errorf("unable to decode package.json %w", err)
+	}
+
+	return pkg, nil
+}
+
+// engine returns the version range that the engines field declares for name.
+// A malformed engines field is ignored, as it is by Yarn itself.
+func (pkg packageJSON) engine(name string) string {
+	var engines map[string]interface{}
+	if err := json.Unmarshal(pkg.Engines, &engines); err != nil {
+		return ""
+	}
+
+	version, _ := engines[name].(string)
+	return strings.TrimSpace(version)
+}
+
+// firstEngine returns the first version range declared for name by the
+// engines field of pkgs.
+func firstEngine(name string, pkgs ...packageJSON) string {
+	for _, pkg := range pkgs {
+		if version := pkg.engine(name); version != "" {
+			return version
+		}
+	}
+
+	return ""
+}
+
+// bins returns the executables declared by the bin field, which is either a
+// single path named after the package or a map of names to paths.
+func (pkg packageJSON) bins() (map[string]string, error) {
+	if len(pkg.Bin) == 0 || string(pkg.Bin) == "null" {
+		return nil, nil
+	}
+
+	var path string
+	if err := json.Unmarshal(pkg.Bin, &path); err == nil {
+		name := pkg.Name
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+		return map[string]string{name: path}, nil
+	}
+
+	var bins map[string]string
+	err := json.Unmarshal(pkg.Bin, &bins)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode package.json bin field %w", err)
+	}
+
+	return bins, nil
+}
+
+// hasScript indicates the presence of a script with the given name.
+func (pkg packageJSON) hasScript(name string) bool {
+	return pkg.Scripts[name] != ""
+}
+
+// lifecycleScripts returns the script with the given name surrounded by the
+// pre<name> and post<name> scripts that Yarn runs along with it. Scripts that
+// are not defined are left out, except for the named script itself, which is
+// replaced by fallback.
+func (pkg packageJSON) lifecycleScripts(name, fallback string) []string {
+	var scripts []string
+	if pkg.hasScript("pre" + name) {
+		scripts = append(scripts, pkg.Scripts["pre"+name])
+	}
+
+	if pkg.hasScript(name) {
+		scripts = append(scripts, pkg.Scripts[name])
+	} else {
+		scripts = append(scripts, fallback)
+	}
+
+	if pkg.hasScript("post" + name) {
+		scripts = append(scripts, pkg.Scripts["post"+name])
+	}
+
+	return scripts
+}
+
+// startScriptName returns the name of the script that starts the application,
+// which can be configured with BP_YARN_START_SCRIPT.
+func startScriptName() string {
+	if name := os.Getenv("BP_YARN_START_SCRIPT"); name != "" {
+		return name
+	}
+
+	return "start"
+}
+
+// lifecycleEnv returns the variables that Yarn sets in the environment of a
+// script it runs: the name, version and config of the package along with the
+// name and text of the script.
+func (pkg packageJSON) lifecycleEnv(event, script string) (packit.Environment, error) {
+	env := packit.Environment{}
+	env.Override("npm_lifecycle_event", event)
+	env.Override("npm_lifecycle_script", script)
+
+	if pkg.Name != "" {
+		env.Override("npm_package_name", pkg.Name)
+	}
+
+	if pkg.Version != "" {
+		env.Override("npm_package_version", pkg.Version)
+	}
+
+	if len(pkg.Config) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(pkg.Config))
+		decoder.UseNumber()
+
+		var config interface{}
+		err := decoder.Decode(&config)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode package.json config field %w", err)
+		}
+
+		addConfigEnv(env, "npm_package_config", config)
+	}
+
+	return env, nil
+}
+
+// addConfigEnv adds a variable for every scalar in value, named after its
+// path through the nested objects and arrays.
+func addConfigEnv(env packit.Environment, name string, value interface{}) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			addConfigEnv(env, name+"_"+invalidEnvironmentCharacters.ReplaceAllString(key, "_"), value[key])
+		}
+
+	case []interface{}:
+		for i, element := range value {
+			addConfigEnv(env, fmt.Sprintf("%s_%d", name, i), element)
+		}
+
+	case nil:
+
+	default:
+		env.Override(name, fmt.Sprint(value))
+	}
+}
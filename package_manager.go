@@ -0,0 +1,67 @@
+package yarnstart
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/fs"
+)
+
+// packageManagers are the values accepted by BP_NODE_PACKAGE_MANAGER.
+var packageManagers = []string{"npm", "pnpm", Yarn}
+
+// foreignLockfiles are the lockfiles written by package managers other than
+// Yarn, in the order in which they are reported.
+var foreignLockfiles = []struct {
+	Name           string
+	PackageManager string
+}{
+	{Name: "package-lock.json", PackageManager: "npm"},
+	{Name: "npm-shrinkwrap.json", PackageManager: "npm"},
+	{Name: "pnpm-lock.yaml", PackageManager: "pnpm"},
+}
+
+// packageManagerOverride returns the package manager named by
+// BP_NODE_PACKAGE_MANAGER, which takes precedence over whatever the project
+// files suggest.
+func packageManagerOverride() (string, error) {
+	value, ok := os.LookupEnv("BP_NODE_PACKAGE_MANAGER")
+	if !ok || value == "" {
+		return "", nil
+	}
+
+	for _, packageManager := range packageManagers {
+		if value == packageManager {
+			return value, nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to parse BP_NODE_PACKAGE_MANAGER value %s: expected one of %s", value, strings.Join(packageManagers, ", "))
+}
+
+// parsePackageManager splits the value of the packageManager field, which has
+// the form <name>@<version> with an optional +<hash> suffix.
+func parsePackageManager(value string) (string, string) {
+	name, version, _ := strings.Cut(strings.TrimSpace(value), "@")
+	version, _, _ = strings.Cut(version, "+")
+	return name, version
+}
+
+// findForeignLockfile returns the first lockfile of another package manager
+// found in path.
+func findForeignLockfile(path string) (string, string, error) {
+	for _, lockfile := range foreignLockfiles {
+		exists, err := fs.Exists(filepath.Join(path, lockfile.Name))
+		if err != nil {
+			return "", "", fmt.Errorf("failed to stat %s: %w", lockfile.Name, err)
+		}
+
+		if exists {
+			return lockfile.Name, lockfile.PackageManager, nil
+		}
+	}
+
+	return "", "", nil
+}
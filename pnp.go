@@ -0,0 +1,101 @@
+package yarnstart
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/fs"
+)
+
+const (
+	// PnPRuntime is the file that sets up Yarn Plug'n'Play module resolution.
+	PnPRuntime = ".pnp.cjs"
+
+	// PnPLoader is the file that sets up Yarn Plug'n'Play resolution for ES
+	// modules.
+	PnPLoader = ".pnp.loader.mjs"
+)
+
+// nodeLinkerPattern matches the top-level nodeLinker setting of a .yarnrc.yml
+// file.
+var nodeLinkerPattern = regexp.MustCompile(`^nodeLinker:\s*["']?([\w-]+)["']?\s*(#.*)?$`)
+
+// usesPnP reports whether the project at path installs its dependencies with
+// Yarn Plug'n'Play rather than into node_modules. That is the case when the
+// nodeLinker setting of .yarnrc.yml is pnp, or when it is not set and the
+// project either has a .yarnrc.yml, which means Yarn Berry where pnp is the
+// default linker, or already has a Plug'n'Play runtime.
+func usesPnP(path string) (bool, error) {
+	file, err := os.Open(filepath.Join(path, ".yarnrc.yml"))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return false, fmt.Errorf("failed to open .yarnrc.yml: %w", err)
+		}
+
+		exists, err := fs.Exists(filepath.Join(path, PnPRuntime))
+		if err != nil {
+			return false, fmt.Errorf("failed to stat %s: %w", PnPRuntime, err)
+		}
+
+		return exists, nil
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if matches := nodeLinkerPattern.FindStringSubmatch(strings.TrimRight(scanner.Text(), "\r")); matches != nil {
+			return matches[1] == "pnp", nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read .yarnrc.yml: %w", err)
+	}
+
+	return true, nil
+}
+
+// pnpNodeOptions returns the NODE_OPTIONS that make node resolve modules
+// through the Plug'n'Play runtime of the project at path, including its ES
+// module loader when there is one.
+func pnpNodeOptions(path string) (string, error) {
+	runtime := filepath.Join(path, PnPRuntime)
+	exists, err := fs.Exists(runtime)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", PnPRuntime, err)
+	}
+
+	if !exists {
+		return "", fmt.Errorf("the project uses Yarn Plug'n'Play but %s does not exist in %s", PnPRuntime, path)
+	}
+
+	options := []string{nodeOption("--require", runtime)}
+
+	loader := filepath.Join(path, PnPLoader)
+	exists, err = fs.Exists(loader)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", PnPLoader, err)
+	}
+
+	if exists {
+		options = append(options, nodeOption("--experimental-loader", (&url.URL{Scheme: "file", Path: loader}).String()))
+	}
+
+	return strings.Join(options, " "), nil
+}
+
+// nodeOption formats a flag and its value for NODE_OPTIONS, which splits on
+// whitespace unless it is inside double quotes.
+func nodeOption(flag, value string) string {
+	if strings.ContainsAny(value, " \t\n\"\\") {
+		value = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	}
+
+	return flag + " " + value
+}
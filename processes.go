@@ -0,0 +1,203 @@
+package yarnstart
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2"
+)
+
+// processTypePattern matches the process types allowed by the buildpack
+// specification.
+var processTypePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// invalidProcessTypeCharacters matches the runs of characters that are not
+// allowed in a process type.
+var invalidProcessTypeCharacters = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// A processScript maps an additional process type to the package.json script
+// that it runs.
+type processScript struct {
+	Type   string
+	Script string
+}
+
+// parseProcessScripts parses the comma-separated <type>=<script> entries of
+// BP_YARN_START_PROCESSES.
+func parseProcessScripts() ([]processScript, error) {
+	value := os.Getenv("BP_YARN_START_PROCESSES")
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var scripts []processScript
+	seen := map[string]bool{"web": true, "no-reload": true}
+	for _, entry := range strings.Split(value, ",") {
+		processType, script, ok := strings.Cut(entry, "=")
+		processType, script = strings.TrimSpace(processType), strings.TrimSpace(script)
+		if !ok || processType == "" || script == "" {
+			return nil, fmt.Errorf("failed to parse BP_YARN_START_PROCESSES value %s: expected entries of the form <type>=<script>", value)
+		}
+
+		if !processTypePattern.MatchString(processType) {
+			return nil, fmt.Errorf("failed to parse BP_YARN_START_PROCESSES value %s: invalid process type %q", value, processType)
+		}
+
+		if seen[processType] {
+			return nil, fmt.Errorf("failed to parse BP_YARN_START_PROCESSES value %s: process type %q is already in use", value, processType)
+		}
+		seen[processType] = true
+
+		scripts = append(scripts, processScript{Type: processType, Script: script})
+	}
+
+	return scripts, nil
+}
+
+// A webCommand is the command that the web process runs, which both Detect
+// and Build derive from the start script in the same way.
+type webCommand struct {
+	scriptFrame
+
+	// Targets are the scripts of other workspaces that the start script was
+	// resolved to, in order.
+	Targets []scriptFrame
+
+	// Entrypoint is the entrypoint that the web process runs when the package
+	// has no start script, and Fallback is the command that runs it.
+	Entrypoint entrypoint
+	Fallback   string
+
+	// Segments are the commands that the web process runs, which are nil when
+	// there is neither a start script nor an entrypoint, and Assignments are
+	// the variables that were lifted out of them.
+	Segments    []string
+	Assignments map[string]string
+}
+
+// resolveWebCommand returns the command that the web process runs for the
+// named script of the package at path. In the direct mode, a start script that
+// does nothing but run the script of another workspace is replaced by that
+// script. Only the default start script falls back to an entrypoint, since a
+// script named by BP_YARN_START_SCRIPT has to exist.
+func resolveWebCommand(inliner *scriptInliner, mode, path string, pkg packageJSON, scriptName string) (webCommand, error) {
+	web := webCommand{scriptFrame: scriptFrame{Path: path, Package: pkg, Script: scriptName}}
+	visited := []scriptFrame{web.scriptFrame}
+	for mode == startModeDirect {
+		target, ok, err := inliner.dispatch(web.Path, web.Package, web.Script)
+		if err != nil {
+			return webCommand{}, err
+		}
+
+		if !ok || slices.ContainsFunc(visited, target.is) {
+			break
+		}
+
+		visited = append(visited, target)
+		web.Targets = append(web.Targets, target)
+		web.scriptFrame = target
+	}
+
+	var err error
+	switch {
+	case web.Package.hasScript(web.Script):
+		web.Segments, err = launchSegments(inliner, mode, web.Path, web.Package, web.Script, "")
+		if err != nil {
+			return webCommand{}, err
+		}
+
+	case scriptName == "start":
+		var found bool
+		web.Entrypoint, found, err = findEntrypoint(path, pkg)
+		if err != nil {
+			return webCommand{}, err
+		}
+
+		if !found {
+			return web, nil
+		}
+
+		web.Fallback = shellJoin(Node, web.Entrypoint.Path)
+		web.Segments, err = launchSegments(inliner, mode, path, pkg, scriptName, web.Fallback)
+		if err != nil {
+			return webCommand{}, err
+		}
+
+	default:
+		return web, nil
+	}
+
+	shouldLiftEnv, err := checkLiftEnvEnabled()
+	if err != nil {
+		return webCommand{}, err
+	}
+
+	if shouldLiftEnv {
+		web.Assignments, web.Segments = liftEnvironment(web.Segments)
+	}
+
+	return web, nil
+}
+
+// launchSegments returns the segments that a launch process runs for the named
+// script of the package at path, or for fallback when there is no such
+// script. In the yarn mode, the processes leave running the scripts, including
+// their pre and post scripts, to yarn run, which node falls back to through
+// yarn node.
+func launchSegments(inliner *scriptInliner, mode, path string, pkg packageJSON, name, fallback string) ([]string, error) {
+	if mode == startModeYarn {
+		if !pkg.hasScript(name) {
+			return []string{Yarn + " " + fallback}, nil
+		}
+		return []string{shellJoin(Yarn, "run", name)}, nil
+	}
+
+	return inliner.scriptSegments(path, pkg, name, fallback)
+}
+
+// hasProcess reports whether processes contains a process of the given type.
+func hasProcess(processes []packit.Process, processType string) bool {
+	for _, process := range processes {
+		if process.Type == processType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setDefaultProcess makes the process type named by
+// BP_YARN_START_DEFAULT_PROCESS the default process. When it is not set and no
+// process is the default yet, the first process becomes the default.
+func setDefaultProcess(processes []packit.Process) error {
+	processType := os.Getenv("BP_YARN_START_DEFAULT_PROCESS")
+	if processType == "" {
+		if len(processes) > 0 && !hasDefaultProcess(processes) {
+			processes[0].Default = true
+		}
+		return nil
+	}
+
+	if !hasProcess(processes, processType) {
+		return fmt.Errorf("failed to parse BP_YARN_START_DEFAULT_PROCESS value %s: no %q process", processType, processType)
+	}
+
+	for i := range processes {
+		processes[i].Default = processes[i].Type == processType
+	}
+
+	return nil
+}
+
+func hasDefaultProcess(processes []packit.Process) bool {
+	for _, process := range processes {
+		if process.Default {
+			return true
+		}
+	}
+
+	return false
+}
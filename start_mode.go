@@ -0,0 +1,84 @@
+package yarnstart
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/fs"
+)
+
+// The launch modes accepted by BP_YARN_START_MODE. In the direct mode, the
+// launch processes run the commands of the scripts themselves; in the yarn
+// mode, they run the scripts through yarn run.
+const (
+	startModeAuto   = "auto"
+	startModeDirect = "direct"
+	startModeYarn   = "yarn"
+)
+
+// startModes are the values accepted by BP_YARN_START_MODE.
+var startModes = []string{startModeAuto, startModeDirect, startModeYarn}
+
+// startMode returns the launch mode chosen by BP_YARN_START_MODE for the
+// project whose workspace root is at rootPath, along with the reason for
+// launching through Yarn. The auto mode launches through Yarn whenever the
+// project uses Yarn Berry, whose plugins, environment files and
+// Plug'n'Play runtime the direct mode cannot reproduce.
+func startMode(rootPath, packageManager string) (string, string, error) {
+	mode := os.Getenv("BP_YARN_START_MODE")
+	switch mode {
+	case "", startModeDirect:
+		return startModeDirect, "", nil
+
+	case startModeYarn:
+		return startModeYarn, "BP_YARN_START_MODE is set to yarn", nil
+
+	case startModeAuto:
+
+	default:
+		return "", "", fmt.Errorf("failed to parse BP_YARN_START_MODE value %s: expected one of %s", mode, strings.Join(startModes, ", "))
+	}
+
+	reason, err := yarnBerryReason(rootPath, packageManager)
+	if err != nil {
+		return "", "", err
+	}
+
+	if reason != "" {
+		return startModeYarn, reason, nil
+	}
+
+	return startModeDirect, "", nil
+}
+
+// yarnBerryReason returns the reason to believe that the project whose
+// workspace root is at rootPath uses Yarn Berry rather than Yarn 1, or an
+// empty string when nothing suggests so.
+func yarnBerryReason(rootPath, packageManager string) (string, error) {
+	exists, err := fs.Exists(filepath.Join(rootPath, ".yarnrc.yml"))
+	if err != nil {
+		return "", fmt.Errorf("failed to stat .yarnrc.yml: %w", err)
+	}
+
+	if exists {
+		return "the project has a .yarnrc.yml", nil
+	}
+
+	pnp, err := usesPnP(rootPath)
+	if err != nil {
+		return "", err
+	}
+
+	if pnp {
+		return "the project uses Plug'n'Play", nil
+	}
+
+	name, version := parsePackageManager(packageManager)
+	if name == Yarn && version != "" && !strings.HasPrefix(version, "1.") {
+		return fmt.Sprintf("package.json declares %s as its package manager", packageManager), nil
+	}
+
+	return "", nil
+}
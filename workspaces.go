@@ -0,0 +1,280 @@
+package yarnstart
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A workspace is a member package of a Yarn workspaces project.
+type workspace struct {
+	// Path is the absolute location of the workspace directory.
+	Path string
+
+	// Package is the package.json of the workspace.
+	Package packageJSON
+}
+
+// workspaceGlobs returns the patterns of the workspaces field, which is either
+// a list of patterns or an object with a packages list.
+func (pkg packageJSON) workspaceGlobs() ([]string, error) {
+	if len(pkg.Workspaces) == 0 || string(pkg.Workspaces) == "null" {
+		return nil, nil
+	}
+
+	var globs []string
+	if err := json.Unmarshal(pkg.Workspaces, &globs); err == nil {
+		return globs, nil
+	}
+
+	var workspaces struct {
+		Packages []string `json:"packages"`
+	}
+	err := json.Unmarshal(pkg.Workspaces, &workspaces)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode package.json workspaces field %w", err)
+	}
+
+	return workspaces.Packages, nil
+}
+
+// findWorkspaces returns the workspaces of the project at root, in the lexical
+// order of their paths. A workspace is a directory with a package.json that
+// matches one of the workspace patterns of the root package.json and none of
+// its negated (!) patterns.
+func findWorkspaces(root string, pkg packageJSON) ([]workspace, error) {
+	globs, err := pkg.workspaceGlobs()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(globs) == 0 {
+		return nil, nil
+	}
+
+	var workspaces []workspace
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if path != root && (entry.Name() == NodeModules || strings.HasPrefix(entry.Name(), ".")) {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if rel == "." || !matchesWorkspaceGlobs(globs, filepath.ToSlash(rel)) {
+			return nil
+		}
+
+		member, err := parsePackageJSON(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return fmt.Errorf("failed to parse workspace %s: %w", rel, err)
+		}
+
+		workspaces = append(workspaces, workspace{Path: path, Package: member})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find workspaces: %w", err)
+	}
+
+	return workspaces, nil
+}
+
+// findWorkspace returns the workspace of the project at root whose package is
+// called name.
+func findWorkspace(root string, pkg packageJSON, name string) (workspace, bool, error) {
+	workspaces, err := findWorkspaces(root, pkg)
+	if err != nil {
+		return workspace{}, false, err
+	}
+
+	for _, member := range workspaces {
+		if member.Package.Name == name {
+			return member, true, nil
+		}
+	}
+
+	return workspace{}, false, nil
+}
+
+func matchesWorkspaceGlobs(globs []string, rel string) bool {
+	var matched bool
+	for _, glob := range globs {
+		if negated, ok := strings.CutPrefix(glob, "!"); ok {
+			if matchGlob(negated, rel) {
+				return false
+			}
+			continue
+		}
+
+		if matchGlob(glob, rel) {
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+// matchGlob reports whether the slash-separated path rel matches pattern,
+// where ** matches any number of directories and every other segment is
+// matched with path.Match.
+func matchGlob(pattern, rel string) bool {
+	patterns := strings.Split(path.Clean(strings.TrimPrefix(pattern, "./")), "/")
+	segments := strings.Split(rel, "/")
+
+	var match func(patterns, segments []string) bool
+	match = func(patterns, segments []string) bool {
+		if len(patterns) == 0 {
+			return len(segments) == 0
+		}
+
+		if patterns[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if match(patterns[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+
+		ok, err := path.Match(patterns[0], segments[0])
+		if err != nil || !ok {
+			return false
+		}
+
+		return match(patterns[1:], segments[1:])
+	}
+
+	return match(patterns, segments)
+}
+
+// startableWorkspaces returns the workspaces of the project at root that have
+// a script called scriptName.
+func startableWorkspaces(root string, pkg packageJSON, scriptName string) ([]workspace, error) {
+	workspaces, err := findWorkspaces(root, pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	var startable []workspace
+	for _, member := range workspaces {
+		if member.Package.hasScript(scriptName) {
+			startable = append(startable, member)
+		}
+	}
+
+	return startable, nil
+}
+
+// workspaceProcessType derives the type of the launch process of a workspace
+// from its package name without the scope, so that @acme/api becomes api.
+// Workspaces without a name are named after their directory.
+func workspaceProcessType(member workspace) string {
+	name := member.Package.Name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	if name == "" {
+		name = filepath.Base(member.Path)
+	}
+
+	return invalidProcessTypeCharacters.ReplaceAllString(name, "-")
+}
+
+// startWorkspaceName returns the name of the workspace package to start, as
+// configured with BP_YARN_START_WORKSPACE.
+func startWorkspaceName() string {
+	return os.Getenv("BP_YARN_START_WORKSPACE")
+}
+
+// findWorkspaceRoot walks up from projectPath, no further than workingDir, to
+// the root of the workspaces project that projectPath is a member of: the
+// nearest directory with a yarn.lock whose package.json has workspace
+// patterns that match projectPath.
+func findWorkspaceRoot(workingDir, projectPath string) (string, bool, error) {
+	dir := projectPath
+	for dir != workingDir {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+
+		rel, err := filepath.Rel(dir, projectPath)
+		if err != nil {
+			return "", false, err
+		}
+
+		_, err = os.Stat(filepath.Join(dir, "yarn.lock"))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return "", false, fmt.Errorf("failed to stat yarn.lock: %w", err)
+		}
+
+		pkg, err := parsePackageJSON(dir)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return "", false, err
+		}
+
+		globs, err := pkg.workspaceGlobs()
+		if err != nil {
+			return "", false, err
+		}
+
+		if matchesWorkspaceGlobs(globs, filepath.ToSlash(rel)) {
+			return dir, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// workspaceRootPath returns the directory with the yarn.lock of the project at
+// projectPath, which is the workspace root when the project is a workspace
+// member without a lockfile of its own.
+func workspaceRootPath(workingDir, projectPath string) (string, error) {
+	_, err := os.Stat(filepath.Join(projectPath, "yarn.lock"))
+	if err == nil {
+		return projectPath, nil
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to stat yarn.lock: %w", err)
+	}
+
+	root, found, err := findWorkspaceRoot(workingDir, projectPath)
+	if err != nil || !found {
+		return projectPath, err
+	}
+
+	return root, nil
+}